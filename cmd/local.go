@@ -4,10 +4,12 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"runtime"
 
+	"github.com/taylorono/go-webservice/internal/framework/health"
 	"github.com/taylorono/go-webservice/internal/framework/testcontainer"
 )
 
@@ -31,6 +33,18 @@ func init() {
 
 		// TODO: create any topics you might need.
 
+		// report the container on the readiness probe
+		health.AddReadinessCheck("kafka", health.CheckerFunc(func(ctx context.Context) error {
+			state, err := kafkaContainer.State(ctx)
+			if err != nil {
+				return err
+			}
+			if !state.Running {
+				return fmt.Errorf("kafka container is %s", state.Status)
+			}
+			return nil
+		}))
+
 		// register the cleanup function
 		cleanup = append(cleanup, func(ctx context.Context) {
 			slog.Info("removing kafka container")
@@ -2,17 +2,22 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/spf13/pflag"
+
 	"github.com/taylorono/go-webservice/internal/api"
 	"github.com/taylorono/go-webservice/internal/framework/config"
+	"github.com/taylorono/go-webservice/internal/framework/health"
 	"github.com/taylorono/go-webservice/internal/framework/logging"
 	"github.com/taylorono/go-webservice/internal/framework/metrics"
 	"github.com/taylorono/go-webservice/internal/framework/web"
@@ -32,6 +37,16 @@ func run(ctx context.Context, w io.Writer, args []string) error {
 	// Load Configuration
 	config.InitConfig(ctx)
 
+	// Configure logging from the loaded configuration
+	if err := logging.Configure(config.Registry); err != nil {
+		return err
+	}
+
+	// Export the OpenAPI document without starting the service
+	if pflag.Arg(0) == "openapi" {
+		return exportOpenAPI(w, pflag.Arg(1))
+	}
+
 	// apply any setup functions
 	startup(ctx)
 
@@ -39,14 +54,121 @@ func run(ctx context.Context, w io.Writer, args []string) error {
 	defer shutdown()
 
 	// Create Metric Reporter
-	prometheusReporter := metrics.NewPrometheusReporter()
+	reporter, err := newReporter(ctx)
+	if err != nil {
+		return err
+	}
+	logging.RegisterMetrics(reporter)
+
+	// Create the web server and its routes
+	webServer := newWebServer(reporter)
+
+	wg := sync.WaitGroup{}
+
+	// Launch the web server in a goroutine
+	wg.Go(func() {
+		if err := webServer.Start(ctx); err != nil {
+			slog.Info("web server stopped", "error", err)
+		}
+	})
+
+	// Start the web server
+	wg.Wait()
+	return nil
+}
 
-	// Register debug logging middleware
-	var middleware []web.Middleware
-	if logging.Level() <= slog.LevelDebug {
-		middleware = append(middleware, logging.HttpLoggingMiddleware)
+// metricsOptions configures the metric reporter. Observations it cannot record are dropped unless METRICS_POLICY is
+// strict, and every metric carries the service, version and pod labels that are set.
+func metricsOptions() ([]metrics.Option, error) {
+	policy, err := metrics.ParsePolicy(config.Registry.GetString("METRICS_POLICY"))
+	if err != nil {
+		return nil, err
 	}
 
+	opts := []metrics.Option{
+		metrics.WithPolicy(policy),
+		metrics.WithNamespace(config.Registry.GetString("METRICS_NAMESPACE")),
+		metrics.WithSubsystem(config.Registry.GetString("METRICS_SUBSYSTEM")),
+		metrics.WithConstLabels(map[string]string{
+			"service": config.Registry.GetString("SERVICE_NAME"),
+			"version": config.Registry.GetString("SERVICE_VERSION"),
+			"pod":     config.Registry.GetString("POD_NAME"),
+		}),
+	}
+	if config.Registry.GetBool("METRICS_GO_COLLECTOR") {
+		opts = append(opts, metrics.WithGoCollector())
+	}
+	if config.Registry.GetBool("METRICS_PROCESS_COLLECTOR") {
+		opts = append(opts, metrics.WithProcessCollector())
+	}
+	if config.Registry.GetBool("METRICS_BUILD_INFO") {
+		opts = append(opts, metrics.WithBuildInfoCollector())
+	}
+	return opts, nil
+}
+
+// newReporter creates the metric reporter of METRICS_REPORTER, a comma separated list of prometheus and otel that
+// records with all of them, prometheus by default.
+func newReporter(ctx context.Context) (metrics.Reporter, error) {
+	opts, err := metricsOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	var reporters []metrics.Reporter
+	for _, name := range strings.Split(config.Registry.GetString("METRICS_REPORTER"), ",") {
+		var reporter metrics.Reporter
+		switch name = strings.TrimSpace(name); name {
+		case "", "prometheus":
+			reporter = metrics.NewPrometheusReporter(opts...)
+		case "otel":
+			if reporter, err = newOTELReporter(ctx, opts); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("invalid metrics reporter %q: must be prometheus or otel", name)
+		}
+		reporters = append(reporters, reporter)
+	}
+
+	if len(reporters) == 1 {
+		return reporters[0], nil
+	}
+	return metrics.NewMultiReporter(reporters...)
+}
+
+// newOTELReporter records with an OpenTelemetry MeterProvider that pushes over OTLP when METRICS_OTLP_PROTOCOL is set
+// and serves /metrics when METRICS_PROMETHEUS is true. METRICS_VIEWS lists the views customizing the metrics.
+func newOTELReporter(ctx context.Context, opts []metrics.Option) (metrics.Reporter, error) {
+	var views []metrics.OTELView
+	if err := config.Registry.UnmarshalKey("METRICS_VIEWS", &views); err != nil {
+		return nil, fmt.Errorf("invalid METRICS_VIEWS: %w", err)
+	}
+
+	provider, err := metrics.NewOTELProvider(ctx, metrics.OTELConfig{
+		ServiceName:     config.Registry.GetString("SERVICE_NAME"),
+		ServiceVersion:  config.Registry.GetString("SERVICE_VERSION"),
+		ServiceInstance: config.Registry.GetString("POD_NAME"),
+		Protocol:        config.Registry.GetString("METRICS_OTLP_PROTOCOL"),
+		Endpoint:        config.Registry.GetString("METRICS_OTLP_ENDPOINT"),
+		Insecure:        config.Registry.GetBool("METRICS_OTLP_INSECURE"),
+		Interval:        config.Registry.GetDuration("METRICS_EXPORT_INTERVAL"),
+		Prometheus:      config.Registry.GetBool("METRICS_PROMETHEUS"),
+		Views:           views,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cleanup = append(cleanup, func(ctx context.Context) {
+		if err := provider.Shutdown(ctx); err != nil {
+			slog.Error("failed to shut down meter provider", "error", err)
+		}
+	})
+	return metrics.NewOTELReporter(append(opts, metrics.WithOTELProvider(provider))...), nil
+}
+
+// newWebServer creates the web server and registers the route handlers of the business logic services.
+func newWebServer(reporter metrics.Reporter) *web.Server {
 	// Create business logic services
 	greeter := service.NewService()
 
@@ -54,25 +176,43 @@ func run(ctx context.Context, w io.Writer, args []string) error {
 	webServer := web.NewServer(
 		web.WithPort(config.Registry.GetString("PORT")),
 		web.WithDebugPort(config.Registry.GetString("DEBUG_PORT")),
+		web.WithDebugRoutes(logging.Routes),
+		web.WithTLS(config.Registry.GetString("TLS_CERT_FILE"), config.Registry.GetString("TLS_KEY_FILE")),
+		web.WithClientCA(config.Registry.GetString("TLS_CLIENT_CA_FILE")),
+		web.WithMaxBodyBytes(config.Registry.GetInt64("MAX_BODY_BYTES")),
+		web.WithErrorDetails(config.Registry.GetBool("ERROR_DETAILS")),
+		web.WithShutdownDelay(config.Registry.GetDuration("SHUTDOWN_DELAY")),
+		web.WithShutdownTimeout(config.Registry.GetDuration("SHUTDOWN_TIMEOUT")),
+		// one access record per request, including those that match no route
+		web.WithServerMiddleware(logging.AccessLogMiddleware),
+		// request dumps are only written at debug level or while enabled on the debug port
 		web.WithMiddleware(logging.HttpLoggingMiddleware),
-		web.WithMetricRegistry(prometheusReporter),
+		web.WithMetricRegistry(reporter),
+		web.WithHealthChecks(health.Default),
 	)
 
 	// Register route handlers
 	api.NewGreeterHandler(greeter).Routes(webServer)
 
-	wg := sync.WaitGroup{}
+	return webServer
+}
 
-	// Launch the web server in a goroutine
-	wg.Go(func() {
-		if err := webServer.Start(ctx); err != nil {
-			slog.Info("web server stopped", "error", err)
+// exportOpenAPI writes the OpenAPI document of the service routes to the file, or to w when no file is given.
+func exportOpenAPI(w io.Writer, file string) error {
+	webServer := newWebServer(metrics.NewPrometheusReporter())
+
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
 		}
-	})
+		defer f.Close()
+		w = f
+	}
 
-	// Start the web server
-	wg.Wait()
-	return nil
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(webServer.OpenAPI())
 }
 
 func startup(ctx context.Context) {
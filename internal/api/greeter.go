@@ -3,6 +3,7 @@ package api
 import (
 	"net/http"
 
+	"github.com/taylorono/go-webservice/internal/framework/web"
 	"github.com/taylorono/go-webservice/internal/service"
 )
 
@@ -19,12 +20,13 @@ func NewGreeterHandler(service *service.Service) *GreeterHandler {
 }
 
 func (s *GreeterHandler) Routes(mux Mux) {
-	mux.HandleFunc("GET /helloworld", s.helloWorld)
+	// plain text unless the client asks for another media type
+	mux.HandleFunc("GET /helloworld", web.PreferMediaType("text/plain")(web.HandlerFuncE(s.helloWorld).ServeHTTP))
+	web.Handle(mux, "GET /hello/{name}", s.Service.Greet)
 }
 
-func (s *GreeterHandler) helloWorld(w http.ResponseWriter, r *http.Request) {
+func (s *GreeterHandler) helloWorld(w http.ResponseWriter, r *http.Request) error {
 	greeting := s.Service.SayHello()
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(greeting))
+	return web.Respond(w, r, http.StatusOK, greeting)
 }
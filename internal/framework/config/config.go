@@ -13,7 +13,7 @@ import (
 
 var (
 	paths          = []string{".", "../.."}
-	onConfigChange func()
+	onConfigChange []func()
 	Registry       *Configuration
 )
 
@@ -58,10 +58,12 @@ func InitConfig(_ context.Context) {
 	}
 
 	// watch for config changes and allow dynamic reload
-	if onConfigChange != nil {
+	if len(onConfigChange) > 0 {
 		registry.WatchConfig()
 		registry.OnConfigChange(func(e fsnotify.Event) {
-			onConfigChange()
+			for _, run := range onConfigChange {
+				run()
+			}
 		})
 	}
 
@@ -78,12 +80,13 @@ func AddConfigPath(path string) {
 	paths = append(paths, path)
 }
 
-// OnConfigChange registers a function that will run when a config change is detected.
+// OnConfigChange registers a function that will run when a config change is detected, functions run in the order they
+// were registered.
 // Must be called before InitConfig
 func OnConfigChange(run func()) {
 	if Registry != nil {
 		panic("cannot register config change handler after InitConfig has been called")
 	}
 
-	onConfigChange = run
+	onConfigChange = append(onConfigChange, run)
 }
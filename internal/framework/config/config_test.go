@@ -2,18 +2,53 @@ package config
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInitConfig(t *testing.T) {
-	var called bool
+	// copy the config so that changing it does not touch testdata
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	b, err := os.ReadFile(filepath.Join("testdata", "config.yaml"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(file, b, 0o600))
+
+	var (
+		mu  sync.Mutex
+		ran []string
+	)
+	calls := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ran...)
+	}
+
 	t.Setenv("APP_ENV", "test")
-	AddConfigPath("testdata")
-	OnConfigChange(func() { called = true })
+	AddConfigPath(dir)
+	OnConfigChange(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, "first")
+	})
+	OnConfigChange(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, "second")
+	})
 	InitConfig(context.Background())
 	assert.Equal(t, "test", Registry.Get("APP_ENV"))
 	assert.Equal(t, "test", Registry.Get("file"))
-	assert.False(t, called)
+	assert.Empty(t, calls(), "callbacks only run on a change")
+
+	require.NoError(t, os.WriteFile(file, []byte("file: changed\n"), 0o600))
+	require.Eventually(t, func() bool { return len(calls()) >= 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"first", "second"}, calls()[:2], "callbacks run in registration order")
+	assert.Equal(t, "changed", Registry.Get("file"))
 }
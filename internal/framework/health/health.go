@@ -0,0 +1,204 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Probe identifies the kubernetes probe a check contributes to.
+type Probe string
+
+const (
+	Liveness  Probe = "livez"
+	Readiness Probe = "readyz"
+	Startup   Probe = "startupz"
+)
+
+var (
+	// Default is the registry used by the package level Add*Check functions. It allows components started before the
+	// web server, such as test containers, to register checks.
+	Default = NewRegistry()
+
+	errTimeout = errors.New("check timed out")
+)
+
+// Checker reports the health of a single component. A nil error is healthy.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to the Checker interface.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Uncached wraps a checker so it runs on every probe, for cheap checks whose change must be reported immediately.
+func Uncached(checker Checker) Checker {
+	return uncached{checker}
+}
+
+type uncached struct {
+	Checker
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Report is the aggregated outcome of every check registered for a probe.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type check struct {
+	sync.Mutex
+	name    string
+	checker Checker
+	result  Result
+	expires time.Time
+}
+
+// Registry holds the checks for each probe and serves them over http.
+type Registry struct {
+	sync.RWMutex
+	timeout  time.Duration
+	cacheTTL time.Duration
+	checks   map[Probe][]*check
+}
+
+// NewRegistry creates an empty registry with the given options.
+func NewRegistry(opts ...OptionFunc) *Registry {
+	r := &Registry{
+		timeout:  2 * time.Second,
+		cacheTTL: time.Second,
+		checks:   make(map[Probe][]*check),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// AddLivenessCheck registers a liveness check on the Default registry.
+func AddLivenessCheck(name string, checker Checker) {
+	Default.AddCheck(Liveness, name, checker)
+}
+
+// AddReadinessCheck registers a readiness check on the Default registry.
+func AddReadinessCheck(name string, checker Checker) {
+	Default.AddCheck(Readiness, name, checker)
+}
+
+// AddStartupCheck registers a startup check on the Default registry.
+func AddStartupCheck(name string, checker Checker) {
+	Default.AddCheck(Startup, name, checker)
+}
+
+// AddCheck registers a named check for the given probe.
+func (r *Registry) AddCheck(probe Probe, name string, checker Checker) {
+	r.Lock()
+	r.checks[probe] = append(r.checks[probe], &check{name: name, checker: checker})
+	r.Unlock()
+}
+
+// Run executes every check registered for the probe concurrently, reusing cached results that have not expired.
+func (r *Registry) Run(ctx context.Context, probe Probe) Report {
+	r.RLock()
+	checks := r.checks[probe]
+	r.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Go(func() { results[i] = r.run(ctx, c) })
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c *check) Result {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	if now.Before(c.expires) {
+		return c.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	// run the check in its own goroutine so a checker that ignores the context cannot block the probe
+	done := make(chan error, 1)
+	go func() { done <- c.checker.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errTimeout
+	}
+
+	c.result = Result{Status: StatusOK, Duration: time.Since(now).String(), Timestamp: now}
+	if err != nil {
+		c.result.Status = StatusFail
+		c.result.Error = err.Error()
+	}
+	if _, ok := c.checker.(uncached); !ok {
+		c.expires = now.Add(r.cacheTTL)
+	}
+
+	return c.result
+}
+
+// Handler returns an http handler reporting the given probe as JSON, responding 503 when any check fails.
+func (r *Registry) Handler(probe Probe) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context(), probe)
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	}
+}
+
+// Routes registers the /livez, /readyz and /startupz endpoints.
+func (r *Registry) Routes(mux *http.ServeMux) {
+	for _, probe := range []Probe{Liveness, Readiness, Startup} {
+		mux.HandleFunc("GET /"+string(probe), r.Handler(probe))
+	}
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Handler(t *testing.T) {
+	registry := NewRegistry()
+	registry.AddCheck(Readiness, "db", CheckerFunc(func(ctx context.Context) error { return nil }))
+	registry.AddCheck(Readiness, "kafka", CheckerFunc(func(ctx context.Context) error { return errors.New("no brokers") }))
+
+	mux := http.NewServeMux()
+	registry.Routes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["db"].Status)
+	assert.Equal(t, "no brokers", report.Checks["kafka"].Error)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	registry := NewRegistry(WithTimeout(10 * time.Millisecond))
+	registry.AddCheck(Liveness, "slow", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+
+	report := registry.Run(context.Background(), Liveness)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, errTimeout.Error(), report.Checks["slow"].Error)
+}
+
+func TestRegistry_Cache(t *testing.T) {
+	var calls, uncachedCalls atomic.Int32
+	registry := NewRegistry(WithCacheTTL(time.Hour))
+	registry.AddCheck(Startup, "counter", CheckerFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}))
+
+	registry.AddCheck(Startup, "uncached", Uncached(CheckerFunc(func(ctx context.Context) error {
+		uncachedCalls.Add(1)
+		return nil
+	})))
+
+	registry.Run(context.Background(), Startup)
+	registry.Run(context.Background(), Startup)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, int32(2), uncachedCalls.Load())
+}
@@ -0,0 +1,19 @@
+package health
+
+import "time"
+
+type OptionFunc func(*Registry)
+
+// WithTimeout sets the maximum time a single check may run before it is reported as failed.
+func WithTimeout(timeout time.Duration) OptionFunc {
+	return func(r *Registry) {
+		r.timeout = timeout
+	}
+}
+
+// WithCacheTTL sets how long a check result is reused before the check is run again.
+func WithCacheTTL(ttl time.Duration) OptionFunc {
+	return func(r *Registry) {
+		r.cacheTTL = ttl
+	}
+}
@@ -0,0 +1,408 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AccessLogFormats are the values accepted by access-log-format, "json" by default and "off" disables the access log.
+var AccessLogFormats = []string{"off", "json", "logfmt", "combined", "ecs", "gcp"}
+
+// defaultSlowRequest is the latency from which requests are always logged unless configured otherwise.
+const defaultSlowRequest = time.Second
+
+// accessLog is where and how access records are written.
+type accessLog struct {
+	write      func(entry *accessEntry)
+	sampleRate float64
+	slow       time.Duration
+}
+
+// accessSink is the format and output of the access log, changing it reopens the output.
+type accessSink struct {
+	format string
+	output string
+	writer writerOptions
+}
+
+var (
+	accessConfig atomic.Pointer[accessLog]
+
+	// accessApplied and accessCloser are guarded by mu
+	accessApplied accessSink
+	accessCloser  io.Closer
+
+	// accessOutput is written by every access log, also those in use by requests started before it was replaced
+	accessOutput = newSwapWriter()
+)
+
+func init() {
+	accessConfig.Store(&accessLog{sampleRate: 1, slow: defaultSlowRequest})
+}
+
+// AccessLogMiddleware creates a middleware that writes one access record per request in the format of
+// access-log-format. Successful requests are sampled by access-log-sample-rate, failed requests and requests taking at
+// least access-log-slow are always logged.
+func AccessLogMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := accessConfig.Load()
+		if log.write == nil {
+			next(w, r)
+			return
+		}
+
+		start := time.Now()
+		body := &countingBody{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+		recorder := &accessRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next(recorder, r)
+
+		latency := time.Since(start)
+		failed := recorder.statusCode >= http.StatusBadRequest
+		slow := log.slow > 0 && latency >= log.slow
+		if !failed && !slow && !sampled(log.sampleRate) {
+			return
+		}
+
+		entry := newAccessEntry(r, start, latency)
+		entry.status, entry.bytesIn, entry.bytesOut = recorder.statusCode, body.n, recorder.size
+		switch {
+		case recorder.statusCode >= http.StatusInternalServerError:
+			entry.level = slog.LevelError
+		case failed || slow:
+			entry.level = slog.LevelWarn
+		}
+		log.write(entry)
+	}
+}
+
+// accessEntry describes a served request.
+type accessEntry struct {
+	time      time.Time
+	level     slog.Level
+	method    string
+	route     string
+	path      string
+	query     string
+	proto     string
+	status    int
+	bytesIn   int64
+	bytesOut  int64
+	latency   time.Duration
+	remoteIP  string
+	userAgent string
+	referer   string
+	requestID string
+}
+
+func newAccessEntry(r *http.Request, start time.Time, latency time.Duration) *accessEntry {
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+
+	pattern := route(r.Context())
+	if pattern == "" {
+		pattern = r.Pattern
+	}
+
+	return &accessEntry{
+		time:      start,
+		method:    r.Method,
+		route:     pattern,
+		path:      r.URL.EscapedPath(),
+		query:     currentRedactor().Query(r.URL.RawQuery),
+		proto:     r.Proto,
+		latency:   latency,
+		remoteIP:  remoteIP,
+		userAgent: r.UserAgent(),
+		referer:   r.Referer(),
+		requestID: RequestID(r.Context()),
+	}
+}
+
+// uri returns the path and the redacted query.
+func (e *accessEntry) uri() string {
+	if e.query == "" {
+		return e.path
+	}
+	return e.path + "?" + e.query
+}
+
+// newAccessWriter returns the function writing entries to w in the format.
+func newAccessWriter(w io.Writer, format string) (func(entry *accessEntry), error) {
+	// the level of an entry decides nothing but its severity field, every entry reaching a writer is written
+	opts := &slog.HandlerOptions{Level: slog.Level(math.MinInt)}
+
+	switch format {
+	case "off":
+		return nil, nil
+	case "json":
+		return slogAccessWriter(slog.NewJSONHandler(w, opts), accessAttrs), nil
+	case "logfmt":
+		return slogAccessWriter(slog.NewTextHandler(w, opts), accessAttrs), nil
+	case "ecs":
+		opts.ReplaceAttr = ecsReplaceAttr
+		return slogAccessWriter(slog.NewJSONHandler(w, opts), ecsAttrs), nil
+	case "gcp":
+		opts.ReplaceAttr = gcpReplaceAttr
+		return slogAccessWriter(slog.NewJSONHandler(w, opts), gcpAttrs), nil
+	case "combined":
+		var mu sync.Mutex
+		return func(entry *accessEntry) {
+			mu.Lock()
+			defer mu.Unlock()
+			_, _ = io.WriteString(w, combined(entry))
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid %s %q: must be one of %s", keyAccessLogFormat, format, strings.Join(AccessLogFormats, ", "))
+	}
+}
+
+func slogAccessWriter(handler slog.Handler, attrs func(entry *accessEntry) []slog.Attr) func(entry *accessEntry) {
+	return func(entry *accessEntry) {
+		record := slog.NewRecord(entry.time, entry.level, "access", 0)
+		record.AddAttrs(attrs(entry)...)
+		_ = handler.Handle(context.Background(), record)
+	}
+}
+
+func accessAttrs(entry *accessEntry) []slog.Attr {
+	return []slog.Attr{
+		slog.String("method", entry.method),
+		slog.String("route", entry.route),
+		slog.String("path", entry.uri()),
+		slog.Int("status", entry.status),
+		slog.Int64("bytes_in", entry.bytesIn),
+		slog.Int64("bytes_out", entry.bytesOut),
+		slog.Duration("latency", entry.latency),
+		slog.String("remote_ip", entry.remoteIP),
+		slog.String("user_agent", entry.userAgent),
+		slog.String("request_id", entry.requestID),
+	}
+}
+
+// ecsAttrs names the fields after the Elastic Common Schema.
+func ecsAttrs(entry *accessEntry) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("ecs.version", "8.11.0"),
+		slog.String("event.dataset", "access"),
+		slog.String("http.request.method", entry.method),
+		slog.String("http.route", entry.route),
+		slog.String("url.path", entry.path),
+		slog.String("http.version", strings.TrimPrefix(entry.proto, "HTTP/")),
+		slog.Int("http.response.status_code", entry.status),
+		slog.Int64("http.request.body.bytes", entry.bytesIn),
+		slog.Int64("http.response.body.bytes", entry.bytesOut),
+		slog.Int64("event.duration", entry.latency.Nanoseconds()),
+		slog.String("client.ip", entry.remoteIP),
+		slog.String("user_agent.original", entry.userAgent),
+		slog.String("http.request.id", entry.requestID),
+	}
+	if entry.query != "" {
+		attrs = append(attrs, slog.String("url.query", entry.query))
+	}
+	if entry.referer != "" {
+		attrs = append(attrs, slog.String("http.request.referrer", entry.referer))
+	}
+	return attrs
+}
+
+func ecsReplaceAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return attr
+	}
+	switch attr.Key {
+	case slog.TimeKey:
+		attr.Key = "@timestamp"
+	case slog.LevelKey:
+		attr = slog.String("log.level", strings.ToLower(attr.Value.String()))
+	case slog.MessageKey:
+		attr.Key = "message"
+	}
+	return attr
+}
+
+// gcpAttrs fills the httpRequest field of Google Cloud Logging structured logs.
+func gcpAttrs(entry *accessEntry) []slog.Attr {
+	return []slog.Attr{
+		slog.Group("httpRequest",
+			slog.String("requestMethod", entry.method),
+			slog.String("requestUrl", entry.uri()),
+			slog.Int("status", entry.status),
+			slog.String("requestSize", strconv.FormatInt(entry.bytesIn, 10)),
+			slog.String("responseSize", strconv.FormatInt(entry.bytesOut, 10)),
+			slog.String("userAgent", entry.userAgent),
+			slog.String("remoteIp", entry.remoteIP),
+			slog.String("referer", entry.referer),
+			slog.String("latency", strconv.FormatFloat(entry.latency.Seconds(), 'f', -1, 64)+"s"),
+			slog.String("protocol", entry.proto),
+		),
+		slog.Group("logging.googleapis.com/labels",
+			slog.String("route", entry.route),
+			slog.String("request_id", entry.requestID),
+		),
+	}
+}
+
+func gcpReplaceAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return attr
+	}
+	switch attr.Key {
+	case slog.LevelKey:
+		severity := "INFO"
+		switch level := attr.Value.Any().(slog.Level); {
+		case level >= slog.LevelError:
+			severity = "ERROR"
+		case level >= slog.LevelWarn:
+			severity = "WARNING"
+		case level < slog.LevelInfo:
+			severity = "DEBUG"
+		}
+		attr = slog.String("severity", severity)
+	case slog.MessageKey:
+		attr.Key = "message"
+	}
+	return attr
+}
+
+// combined formats the entry in the Apache combined log format.
+func combined(entry *accessEntry) string {
+	bytesOut := "-"
+	if entry.bytesOut > 0 {
+		bytesOut = strconv.FormatInt(entry.bytesOut, 10)
+	}
+	return fmt.Sprintf("%s - - [%s] %s %d %s %s %s\n",
+		entry.remoteIP,
+		entry.time.Format("02/Jan/2006:15:04:05 -0700"),
+		quote(entry.method+" "+entry.uri()+" "+entry.proto),
+		entry.status,
+		bytesOut,
+		quote(entry.referer),
+		quote(entry.userAgent),
+	)
+}
+
+func quote(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+	return strconv.Quote(s)
+}
+
+// openAccessLog opens the output of the access log and returns the function replacing the access log with it. The
+// output is reopened only when the format or output changed. Requests in flight write their entry in the previous format
+// to the new output, or discard it when the access log is turned off.
+func openAccessLog(next accessSink, sampleRate float64, slow time.Duration) (apply func(), err error) {
+	log := &accessLog{write: accessConfig.Load().write, sampleRate: sampleRate, slow: slow}
+	if next == accessApplied {
+		return func() { accessConfig.Store(log) }, nil
+	}
+
+	write, err := newAccessWriter(accessOutput, next.format)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		w          io.Writer = io.Discard
+		nextCloser io.Closer
+	)
+	if next.format != "off" {
+		if w, nextCloser, err = open(keyAccessLogOutput, next.output, next.writer); err != nil {
+			return nil, err
+		}
+	}
+
+	return func() {
+		accessOutput.swap(w)
+		log.write = write
+		accessConfig.Store(log)
+
+		if accessCloser != nil {
+			_ = accessCloser.Close()
+		}
+		accessApplied, accessCloser = next, nextCloser
+	}, nil
+}
+
+// countingBody counts the bytes of the request body read by the handler.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// accessRecorder records the status and size of the response.
+type accessRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	size        int64
+}
+
+func (r *accessRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		// informational responses are followed by the final one
+		r.wroteHeader = statusCode >= 200
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *accessRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+// ReadFrom lets the underlying writer copy directly, for example with sendfile.
+func (r *accessRecorder) ReadFrom(src io.Reader) (int64, error) {
+	r.wroteHeader = true
+	var (
+		n   int64
+		err error
+	)
+	if readerFrom, ok := r.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = readerFrom.ReadFrom(src)
+	} else {
+		n, err = io.Copy(r.ResponseWriter, src)
+	}
+	r.size += n
+	return n, err
+}
+
+func (r *accessRecorder) Flush() {
+	r.wroteHeader = true
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *accessRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *accessRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessWriter(t *testing.T) {
+	entry := &accessEntry{
+		time:      time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+		method:    http.MethodGet,
+		route:     "GET /hello/{name}",
+		path:      "/hello/bob",
+		query:     "lang=en",
+		proto:     "HTTP/1.1",
+		status:    http.StatusOK,
+		bytesIn:   0,
+		bytesOut:  12,
+		latency:   1500 * time.Millisecond,
+		remoteIP:  "10.0.0.1",
+		userAgent: "curl/8.0",
+		requestID: "abc",
+	}
+
+	tests := map[string]struct {
+		format string
+		want   map[string]any
+		line   string
+	}{
+		"json": {
+			format: "json",
+			want: map[string]any{
+				"msg": "access", "method": "GET", "route": "GET /hello/{name}", "path": "/hello/bob?lang=en",
+				"status": 200.0, "bytes_out": 12.0, "latency": 1.5e9, "remote_ip": "10.0.0.1", "request_id": "abc",
+			},
+		},
+		"ecs": {
+			format: "ecs",
+			want: map[string]any{
+				"message": "access", "log.level": "info", "@timestamp": "2024-03-01T12:30:00Z", "http.request.method": "GET",
+				"url.path": "/hello/bob", "url.query": "lang=en", "http.response.status_code": 200.0,
+				"http.response.body.bytes": 12.0, "event.duration": 1.5e9, "client.ip": "10.0.0.1",
+				"user_agent.original": "curl/8.0", "http.request.id": "abc",
+			},
+		},
+		"gcp": {
+			format: "gcp",
+			want: map[string]any{
+				"message": "access", "severity": "INFO",
+				"httpRequest": map[string]any{
+					"requestMethod": "GET", "requestUrl": "/hello/bob?lang=en", "status": 200.0, "requestSize": "0",
+					"responseSize": "12", "userAgent": "curl/8.0", "remoteIp": "10.0.0.1", "referer": "",
+					"latency": "1.5s", "protocol": "HTTP/1.1",
+				},
+				"logging.googleapis.com/labels": map[string]any{"route": "GET /hello/{name}", "request_id": "abc"},
+			},
+		},
+		"logfmt": {
+			format: "logfmt",
+			line:   `level=INFO msg=access method=GET route="GET /hello/{name}" path="/hello/bob?lang=en" status=200 bytes_in=0 bytes_out=12 latency=1.5s remote_ip=10.0.0.1 user_agent=curl/8.0 request_id=abc`,
+		},
+		"combined": {
+			format: "combined",
+			line:   `10.0.0.1 - - [01/Mar/2024:12:30:00 +0000] "GET /hello/bob?lang=en HTTP/1.1" 200 12 "-" "curl/8.0"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			write, err := newAccessWriter(&out, tt.format)
+			require.NoError(t, err)
+			write(entry)
+
+			if tt.line != "" {
+				assert.Contains(t, out.String(), tt.line)
+				return
+			}
+
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(out.Bytes(), &record))
+			for key, want := range tt.want {
+				assert.Equal(t, want, record[key], key)
+			}
+		})
+	}
+
+	_, err := newAccessWriter(&bytes.Buffer{}, "xml")
+	assert.Error(t, err)
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var entries []*accessEntry
+	previous := accessConfig.Load()
+	accessConfig.Store(&accessLog{
+		write:      func(entry *accessEntry) { entries = append(entries, entry) },
+		sampleRate: 0,
+		slow:       50 * time.Millisecond,
+	})
+	t.Cleanup(func() { accessConfig.Store(previous) })
+
+	handler := AccessLogMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		SetRoute(r.Context(), "POST /items/{id}")
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.URL.Query().Has("slow") {
+			time.Sleep(60 * time.Millisecond)
+		}
+		if r.URL.Query().Has("fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = w.Write([]byte("created"))
+	})
+	serve := func(target string) {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"name":"x"}`))
+		req.Body = http.MaxBytesReader(nil, req.Body, 1024)
+		handler(httptest.NewRecorder(), req.WithContext(WithRequest(req.Context(), "id-1", req.Method)))
+	}
+
+	serve("/items/1")
+	assert.Empty(t, entries, "successful requests are sampled")
+
+	serve("/items/1?fail&token=secret")
+	require.Len(t, entries, 1)
+	assert.Equal(t, http.StatusInternalServerError, entries[0].status)
+	assert.Equal(t, "POST /items/{id}", entries[0].route)
+	assert.Equal(t, "id-1", entries[0].requestID)
+	assert.Equal(t, "fail=&token=%5BREDACTED%5D", entries[0].query)
+	assert.Equal(t, int64(12), entries[0].bytesIn)
+	assert.Equal(t, int64(7), entries[0].bytesOut)
+
+	serve("/items/1?slow")
+	require.Len(t, entries, 2, "slow requests are always logged")
+	assert.GreaterOrEqual(t, entries[1].latency, 50*time.Millisecond)
+}
@@ -0,0 +1,357 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTemporaryDuration = 10 * time.Minute
+	maxTemporaryDuration     = time.Hour
+)
+
+// Routes registers the admin endpoints intended for the debug port:
+//
+//	GET    /debug/logging/level       the log level and per logger levels
+//	PUT    /debug/logging/level       temporarily change the level, {"level":"debug","logger":"web","duration":"5m"}
+//	GET    /debug/logging/dumps       the active dump rules
+//	POST   /debug/logging/dumps       temporarily dump requests, {"route":"GET /hello/{name}","header":"X-Debug","value":"1","sample":0.1,"duration":"5m"}
+//	DELETE /debug/logging/dumps/{id}  remove a dump rule early
+//
+// Every change reverts by itself once its duration, 10 minutes by default and at most an hour, has passed.
+func Routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /debug/logging/level", getLevel)
+	mux.HandleFunc("PUT /debug/logging/level", putLevel)
+	mux.HandleFunc("GET /debug/logging/dumps", getDumps)
+	mux.HandleFunc("POST /debug/logging/dumps", postDump)
+	mux.HandleFunc("DELETE /debug/logging/dumps/{id}", deleteDump)
+}
+
+type levelStatus struct {
+	Level   string            `json:"level"`
+	Loggers map[string]string `json:"loggers,omitempty"`
+	Reverts map[string]string `json:"reverts,omitempty"`
+}
+
+type levelChange struct {
+	Level    string `json:"level"`
+	Logger   string `json:"logger,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+func getLevel(w http.ResponseWriter, _ *http.Request) {
+	status := levelStatus{Level: Level().String(), Loggers: map[string]string{}, Reverts: map[string]string{}}
+	if levels := overrides.Load(); levels != nil {
+		for name, lvl := range *levels {
+			status.Loggers[name] = lvl.String()
+		}
+	}
+	for name, at := range temporary.revertTimes() {
+		if name == "" {
+			name = "*"
+		}
+		status.Reverts[name] = at.Format(time.RFC3339)
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func putLevel(w http.ResponseWriter, r *http.Request) {
+	var change levelChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		http.Error(w, fmt.Sprintf("invalid body: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(change.Level)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration, err := temporaryDuration(change.Duration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	temporary.set(change.Logger, lvl, duration)
+	slog.InfoContext(r.Context(), "log level changed",
+		slog.String("logger", change.Logger),
+		slog.String("level", lvl.String()),
+		slog.Duration("duration", duration),
+	)
+	getLevel(w, r)
+}
+
+func temporaryDuration(raw string) (time.Duration, error) {
+	if raw == "" {
+		return defaultTemporaryDuration, nil
+	}
+
+	duration, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %w", err)
+	}
+	if duration <= 0 || duration > maxTemporaryDuration {
+		return 0, fmt.Errorf("duration must be positive and at most %s", maxTemporaryDuration)
+	}
+	return duration, nil
+}
+
+// temporary tracks level changes made through the admin endpoints and reverts them, keyed by logger name with "" for
+// the global level.
+var temporary = &levelChanges{pending: map[string]*pendingLevel{}}
+
+type levelChanges struct {
+	sync.Mutex
+	pending map[string]*pendingLevel
+}
+
+type pendingLevel struct {
+	previous    slog.Level
+	hadPrevious bool
+	timer       *time.Timer
+	revertsAt   time.Time
+}
+
+func (c *levelChanges) set(logger string, lvl slog.Level, duration time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	// repeated changes extend the first one, reverting to the level from before any of them
+	pending, ok := c.pending[logger]
+	if ok {
+		pending.timer.Stop()
+	} else {
+		pending = &pendingLevel{}
+		pending.previous, pending.hadPrevious = currentLevel(logger)
+		c.pending[logger] = pending
+	}
+
+	setLevel(logger, lvl, true)
+	pending.revertsAt = time.Now().Add(duration)
+	pending.timer = time.AfterFunc(duration, func() { c.revert(logger, pending) })
+}
+
+func (c *levelChanges) revert(logger string, pending *pendingLevel) {
+	c.Lock()
+	defer c.Unlock()
+
+	// the change was replaced or cancelled in the meantime
+	if c.pending[logger] != pending {
+		return
+	}
+	delete(c.pending, logger)
+
+	setLevel(logger, pending.previous, pending.hadPrevious)
+	slog.Info("log level reverted", slog.String("logger", logger), slog.String("level", Level().String()))
+}
+
+// cancel forgets every pending change without reverting it, used when the configuration is applied again.
+func (c *levelChanges) cancel() {
+	c.Lock()
+	defer c.Unlock()
+
+	for logger, pending := range c.pending {
+		pending.timer.Stop()
+		delete(c.pending, logger)
+	}
+}
+
+func (c *levelChanges) revertTimes() map[string]time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	times := make(map[string]time.Time, len(c.pending))
+	for logger, pending := range c.pending {
+		times[logger] = pending.revertsAt
+	}
+	return times
+}
+
+func currentLevel(logger string) (slog.Level, bool) {
+	if logger == "" {
+		return level.Level(), true
+	}
+	if levels := overrides.Load(); levels != nil {
+		lvl, ok := (*levels)[logger]
+		return lvl, ok
+	}
+	return 0, false
+}
+
+// setLevel sets the global level or the override of the logger, removing the override when set is false.
+func setLevel(logger string, lvl slog.Level, set bool) {
+	if logger == "" {
+		level.Set(lvl)
+		return
+	}
+
+	levels := map[string]slog.Level{}
+	if current := overrides.Load(); current != nil {
+		levels = maps.Clone(*current)
+	}
+	if set {
+		levels[logger] = lvl
+	} else {
+		delete(levels, logger)
+	}
+	overrides.Store(&levels)
+}
+
+// dumpRule enables request dumps for a sample of the requests matching its route pattern and header until it expires.
+// Empty fields match every request.
+type dumpRule struct {
+	ID      string    `json:"id"`
+	Route   string    `json:"route,omitempty"`
+	Header  string    `json:"header,omitempty"`
+	Value   string    `json:"value,omitempty"`
+	Sample  float64   `json:"sample"`
+	Expires time.Time `json:"expires"`
+}
+
+type dumpRequest struct {
+	Route    string   `json:"route"`
+	Header   string   `json:"header"`
+	Value    string   `json:"value"`
+	Sample   *float64 `json:"sample"`
+	Duration string   `json:"duration"`
+}
+
+var dumps = &dumpRules{rules: map[string]*dumpRule{}}
+
+type dumpRules struct {
+	sync.RWMutex
+	rules  map[string]*dumpRule
+	active atomic.Int32
+	nextID int
+}
+
+func (d *dumpRules) add(rule *dumpRule, duration time.Duration) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.nextID++
+	rule.ID = strconv.Itoa(d.nextID)
+	rule.Expires = time.Now().Add(duration)
+	d.rules[rule.ID] = rule
+	d.active.Store(int32(len(d.rules)))
+
+	time.AfterFunc(duration, func() {
+		if d.remove(rule.ID) {
+			slog.Info("request dumps disabled", slog.String("id", rule.ID))
+		}
+	})
+}
+
+func (d *dumpRules) remove(id string) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	_, ok := d.rules[id]
+	delete(d.rules, id)
+	d.active.Store(int32(len(d.rules)))
+	return ok
+}
+
+func (d *dumpRules) list() []*dumpRule {
+	d.RLock()
+	defer d.RUnlock()
+
+	rules := make([]*dumpRule, 0, len(d.rules))
+	rules = slices.AppendSeq(rules, maps.Values(d.rules))
+	slices.SortFunc(rules, func(a, b *dumpRule) int { return a.Expires.Compare(b.Expires) })
+	return rules
+}
+
+// match reports whether an unexpired rule matches the request and the highest sample rate of the matching rules, it is
+// cheap while no rules are active.
+func (d *dumpRules) match(r *http.Request) (float64, bool) {
+	if d.active.Load() == 0 {
+		return 0, false
+	}
+
+	d.RLock()
+	defer d.RUnlock()
+
+	var (
+		rate    float64
+		matched bool
+	)
+	now := time.Now()
+	for _, rule := range d.rules {
+		if now.After(rule.Expires) {
+			continue
+		}
+		if rule.Route != "" && rule.Route != r.Pattern {
+			continue
+		}
+		if rule.Header != "" {
+			values := r.Header.Values(rule.Header)
+			if len(values) == 0 || rule.Value != "" && !slices.Contains(values, rule.Value) {
+				continue
+			}
+		}
+		rate, matched = max(rate, rule.Sample), true
+	}
+	return rate, matched
+}
+
+func getDumps(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, dumps.list())
+}
+
+func postDump(w http.ResponseWriter, r *http.Request) {
+	var request dumpRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid body: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	duration, err := temporaryDuration(request.Duration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sample := 1.0
+	if request.Sample != nil {
+		sample = *request.Sample
+	}
+	if sample <= 0 || sample > 1 {
+		http.Error(w, "sample must be greater than 0 and at most 1", http.StatusBadRequest)
+		return
+	}
+
+	rule := &dumpRule{Route: request.Route, Header: request.Header, Value: request.Value, Sample: sample}
+	dumps.add(rule, duration)
+	slog.InfoContext(r.Context(), "request dumps enabled",
+		slog.String("id", rule.ID),
+		slog.String("route", rule.Route),
+		slog.String("header", rule.Header),
+		slog.Duration("duration", duration),
+	)
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+func deleteDump(w http.ResponseWriter, r *http.Request) {
+	if !dumps.remove(r.PathValue("id")) {
+		http.Error(w, "dump rule not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func adminRequest(t *testing.T, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestRoutes_Level(t *testing.T) {
+	Capture(t)
+	level.Set(slog.LevelInfo)
+	t.Cleanup(func() { temporary.cancel(); level.Set(slog.LevelInfo) })
+
+	rec := adminRequest(t, http.MethodPut, "/debug/logging/level", `{"level":"debug","duration":"50ms"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var status levelStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	assert.Equal(t, "DEBUG", status.Level)
+	assert.Contains(t, status.Reverts, "*")
+
+	rec = adminRequest(t, http.MethodPut, "/debug/logging/level", `{"level":"error","logger":"web","duration":"50ms"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, slog.LevelError, levelFor("web.tls"))
+
+	assert.Eventually(t, func() bool {
+		return Level() == slog.LevelInfo && levelFor("web") == slog.LevelInfo
+	}, time.Second, 10*time.Millisecond, "changes revert after their duration")
+
+	assert.Equal(t, http.StatusBadRequest, adminRequest(t, http.MethodPut, "/debug/logging/level", `{"level":"loud"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, adminRequest(t, http.MethodPut, "/debug/logging/level", `{"level":"debug","duration":"2h"}`).Code)
+}
+
+func TestRoutes_Dumps(t *testing.T) {
+	previous := level.Level()
+	t.Cleanup(func() { level.Set(previous) })
+
+	for _, lvl := range []slog.Level{slog.LevelInfo, slog.LevelWarn} {
+		t.Run(lvl.String(), func(t *testing.T) {
+			// capture records the configured level lets through
+			logs := Capture(t)
+			slog.SetDefault(slog.New(NewContextHandler(&levelHandler{Handler: &captureHandler{recorder: logs}})))
+			level.Set(lvl)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /dumped", HttpLoggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				slog.InfoContext(r.Context(), "handled")
+				_, _ = w.Write([]byte("ok"))
+			}))
+			serve := func(header string) {
+				req := httptest.NewRequest(http.MethodGet, "/dumped", nil)
+				if header != "" {
+					req.Header.Set("X-Debug", header)
+				}
+				mux.ServeHTTP(httptest.NewRecorder(), req.WithContext(context.Background()))
+			}
+
+			serve("1")
+			_, ok := logs.Find("HTTP Request")
+			assert.False(t, ok, "no dumps without a rule")
+
+			rec := adminRequest(t, http.MethodPost, "/debug/logging/dumps", `{"route":"GET /dumped","header":"X-Debug","value":"1","duration":"1m"}`)
+			require.Equal(t, http.StatusCreated, rec.Code)
+			var rule dumpRule
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&rule))
+			logs.Reset()
+
+			serve("2")
+			_, ok = logs.Find("HTTP Request")
+			assert.False(t, ok, "header value must match")
+
+			serve("1")
+			_, ok = logs.Find("HTTP Request")
+			assert.True(t, ok, "rule dumps are written whatever the level")
+			response, ok := logs.Find("HTTP Response")
+			require.True(t, ok)
+			assert.Equal(t, slog.LevelInfo, response.Level)
+			_, ok = logs.Find("handled")
+			assert.Equal(t, lvl <= slog.LevelInfo, ok, "the handler keeps logging at the configured level")
+
+			assert.Equal(t, http.StatusNoContent, adminRequest(t, http.MethodDelete, "/debug/logging/dumps/"+rule.ID, "").Code)
+			assert.Equal(t, "[]\n", adminRequest(t, http.MethodGet, "/debug/logging/dumps", "").Body.String())
+
+			logs.Reset()
+			serve("1")
+			_, ok = logs.Find("HTTP Request")
+			assert.False(t, ok)
+		})
+	}
+}
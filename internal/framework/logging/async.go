@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultAsyncBuffer is how many records an AsyncWriter holds unless configured otherwise.
+const defaultAsyncBuffer = 4096
+
+// AsyncWriter writes to the underlying writer on a background goroutine so that logging never waits for slow output. It
+// holds up to a fixed number of pending writes in a ring buffer, once that is full the oldest pending write is dropped.
+type AsyncWriter struct {
+	w      io.Writer
+	onDrop func()
+
+	mu      sync.Mutex
+	ring    [][]byte
+	head    int
+	pending int
+	closed  bool
+
+	wake    chan struct{}
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+// NewAsyncWriter starts writing to w in the background, holding at most size pending writes. onDrop, if not nil, is
+// called for every dropped write.
+func NewAsyncWriter(w io.Writer, size int, onDrop func()) *AsyncWriter {
+	if size <= 0 {
+		size = defaultAsyncBuffer
+	}
+
+	a := &AsyncWriter{
+		w:      w,
+		onDrop: onDrop,
+		ring:   make([][]byte, size),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Write queues a copy of p and never blocks on the underlying writer, it fails only once the writer is closed.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+
+	dropped := a.pending == len(a.ring)
+	if dropped {
+		a.ring[a.head] = nil
+		a.head = (a.head + 1) % len(a.ring)
+		a.pending--
+	}
+	a.ring[(a.head+a.pending)%len(a.ring)] = append([]byte(nil), p...)
+	a.pending++
+	a.mu.Unlock()
+
+	if dropped {
+		a.dropped.Add(1)
+		if a.onDrop != nil {
+			a.onDrop()
+		}
+	}
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Dropped returns how many writes were dropped because the buffer was full.
+func (a *AsyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+
+	var batch [][]byte
+	for {
+		a.mu.Lock()
+		for a.pending > 0 {
+			batch = append(batch, a.ring[a.head])
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+			a.pending--
+		}
+		closed := a.closed
+		a.mu.Unlock()
+
+		for _, p := range batch {
+			_, _ = a.w.Write(p)
+		}
+		clear(batch)
+		batch = batch[:0]
+
+		if closed {
+			return
+		}
+		<-a.wake
+	}
+}
+
+// Close writes the pending writes and stops the background goroutine. It does not close the underlying writer.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.mu.Unlock()
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+	<-a.done
+	return nil
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingWriter blocks every write until released.
+type blockingWriter struct {
+	mu      sync.Mutex
+	out     bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.release
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.out.Write(p)
+}
+
+func TestAsyncWriter(t *testing.T) {
+	w := &blockingWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+	var drops int
+	async := NewAsyncWriter(w, 2, func() { drops++ })
+
+	_, err := async.Write([]byte("a"))
+	require.NoError(t, err)
+	// the writer goroutine is now stuck writing "a"
+	<-w.started
+
+	for _, p := range []string{"b", "c", "d"} {
+		n, err := async.Write([]byte(p))
+		require.NoError(t, err)
+		assert.Equal(t, 1, n, "writes never block")
+	}
+	assert.Equal(t, uint64(1), async.Dropped(), "the oldest pending write is dropped")
+	assert.Equal(t, 1, drops)
+
+	close(w.release)
+	require.NoError(t, async.Close())
+	assert.Equal(t, "acd", w.out.String(), "pending writes are written on close")
+
+	_, err = async.Write([]byte("e"))
+	assert.Error(t, err)
+}
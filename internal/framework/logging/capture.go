@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// Record is a log record captured by Capture. Attributes in groups are keyed by their dotted path, such as "http.status".
+type Record struct {
+	Level   slog.Level
+	Message string
+	Attrs   map[string]any
+}
+
+// Recorder holds the records captured during a test.
+type Recorder struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// Capture makes the default logger record every message, at any level, for the rest of the test and restores the previous
+// logger when the test ends. As it replaces the process wide default logger it must not be used by parallel tests.
+func Capture(t testing.TB) *Recorder {
+	t.Helper()
+
+	recorder := &Recorder{}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(NewContextHandler(&captureHandler{recorder: recorder})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return recorder
+}
+
+// Records returns the records captured so far.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.records)
+}
+
+// Find returns the first record with the message.
+func (r *Recorder) Find(message string) (Record, bool) {
+	for _, record := range r.Records() {
+		if record.Message == message {
+			return record, true
+		}
+	}
+	return Record{}, false
+}
+
+// Reset discards the records captured so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = nil
+}
+
+type captureHandler struct {
+	recorder *Recorder
+	attrs    []slog.Attr
+	group    string
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *captureHandler) Handle(_ context.Context, record slog.Record) error {
+	captured := Record{Level: record.Level, Message: record.Message, Attrs: map[string]any{}}
+	for _, attr := range h.attrs {
+		addAttr(captured.Attrs, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		addAttr(captured.Attrs, h.group, attr)
+		return true
+	})
+
+	h.recorder.mu.Lock()
+	defer h.recorder.mu.Unlock()
+	h.recorder.records = append(h.recorder.records, captured)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	qualified := slices.Clone(h.attrs)
+	for _, attr := range attrs {
+		qualified = append(qualified, slog.Attr{Key: prefixed(h.group, attr.Key), Value: attr.Value})
+	}
+	return &captureHandler{recorder: h.recorder, attrs: qualified, group: h.group}
+}
+
+func (h *captureHandler) WithGroup(name string) slog.Handler {
+	return &captureHandler{recorder: h.recorder, attrs: h.attrs, group: prefixed(h.group, name)}
+}
+
+func addAttr(attrs map[string]any, group string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		for _, member := range value.Group() {
+			addAttr(attrs, prefixed(group, attr.Key), member)
+		}
+		return
+	}
+	attrs[prefixed(group, attr.Key)] = value.Any()
+}
+
+func prefixed(group, key string) string {
+	if group == "" || key == "" {
+		return group + key
+	}
+	return group + "." + key
+}
@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+)
+
+type (
+	scopeKey struct{}
+	attrsKey struct{}
+)
+
+// scope describes the request a context belongs to. The route is only known once the request has been matched, so it is
+// set after the scope has been created.
+type scope struct {
+	requestID string
+	method    string
+	route     atomic.Pointer[string]
+}
+
+// WithRequest returns a context whose log records carry the request id and method.
+func WithRequest(ctx context.Context, requestID, method string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{requestID: requestID, method: method})
+}
+
+// SetRoute records the route pattern the request of ctx matched, adding it to its log records.
+func SetRoute(ctx context.Context, pattern string) {
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		s.route.Store(&pattern)
+	}
+}
+
+// RequestID returns the id of the request of ctx or "" when ctx does not belong to a request.
+func RequestID(ctx context.Context) string {
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		return s.requestID
+	}
+	return ""
+}
+
+// route returns the route pattern the request of ctx matched or "" when it has not been matched.
+func route(ctx context.Context) string {
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		if route := s.route.Load(); route != nil {
+			return *route
+		}
+	}
+	return ""
+}
+
+// WithAttrs returns a context whose log records carry the attributes in addition to those already added to ctx. The
+// arguments are key value pairs or slog.Attr values, as for slog.Logger.With.
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	attrs := slog.Group("", args...).Value.Group()
+	if len(attrs) == 0 {
+		return ctx
+	}
+
+	existing, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return context.WithValue(ctx, attrsKey{}, append(slices.Clip(existing), attrs...))
+}
+
+// FromContext returns the default logger bound to ctx, its records carry the request and attributes of ctx even when
+// logged without a context.
+func FromContext(ctx context.Context) *slog.Logger {
+	handler := slog.Default().Handler()
+	if _, ok := handler.(*ContextHandler); !ok {
+		handler = NewContextHandler(handler)
+	}
+	return slog.New(&boundHandler{Handler: handler, ctx: ctx})
+}
+
+// contextAttrs returns the request attributes followed by those added with WithAttrs.
+func contextAttrs(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		attrs = append(attrs, slog.String("request_id", s.requestID), slog.String("method", s.method))
+		if route := s.route.Load(); route != nil {
+			attrs = append(attrs, slog.String("route", *route))
+		}
+	}
+	if added, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		attrs = append(attrs, added...)
+	}
+	return attrs
+}
+
+// ContextHandler adds the request id, method and route of the context and the attributes added with WithAttrs to every
+// record. They are always added at the top level, outside any group opened with WithGroup.
+type ContextHandler struct {
+	slog.Handler
+
+	// base is the wrapped handler before any WithAttrs or WithGroup, the scoped operations replayed in order reproduce
+	// Handler on top of the context attributes when the handler has groups.
+	base   slog.Handler
+	scoped []func(slog.Handler) slog.Handler
+	groups bool
+}
+
+// NewContextHandler wraps handler so that records logged with a request context carry its request_id, method and route,
+// followed by the attributes added with WithAttrs.
+func NewContextHandler(handler slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: handler, base: handler}
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := contextAttrs(ctx)
+	if len(attrs) == 0 {
+		return h.Handler.Handle(ctx, record)
+	}
+
+	if !h.groups {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+		return h.Handler.Handle(ctx, record)
+	}
+
+	handler := h.base.WithAttrs(attrs)
+	for _, scope := range h.scoped {
+		handler = scope(handler)
+	}
+	return handler.Handle(ctx, record)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(h.Handler.WithAttrs(attrs), func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) }, false)
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return h.with(h.Handler.WithGroup(name), func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) }, name != "")
+}
+
+func (h *ContextHandler) with(handler slog.Handler, scope func(slog.Handler) slog.Handler, group bool) *ContextHandler {
+	return &ContextHandler{
+		Handler: handler,
+		base:    h.base,
+		scoped:  append(slices.Clip(h.scoped), scope),
+		groups:  h.groups || group,
+	}
+}
+
+// boundHandler logs every record with the context it was created for.
+type boundHandler struct {
+	slog.Handler
+	ctx context.Context
+}
+
+func (h *boundHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.Handler.Enabled(h.ctx, level)
+}
+
+func (h *boundHandler) Handle(_ context.Context, record slog.Record) error {
+	return h.Handler.Handle(h.ctx, record)
+}
+
+func (h *boundHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &boundHandler{Handler: h.Handler.WithAttrs(attrs), ctx: h.ctx}
+}
+
+func (h *boundHandler) WithGroup(name string) slog.Handler {
+	return &boundHandler{Handler: h.Handler.WithGroup(name), ctx: h.ctx}
+}
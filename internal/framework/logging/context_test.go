@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextHandler(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(NewContextHandler(slog.NewJSONHandler(&out, nil)))
+
+	ctx := WithRequest(context.Background(), "req-1", "GET")
+	SetRoute(ctx, "GET /users/{id}")
+	logger.InfoContext(ctx, "hello")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "GET", record["method"])
+	assert.Equal(t, "GET /users/{id}", record["route"])
+
+	out.Reset()
+	logger.Info("no request")
+	assert.NotContains(t, out.String(), "request_id")
+}
+
+func TestFromContext(t *testing.T) {
+	logs := Capture(t)
+
+	ctx := WithRequest(context.Background(), "req-1", "POST")
+	ctx = WithAttrs(ctx, "tenant", "acme")
+	ctx = WithAttrs(ctx, slog.String("user", "alice"))
+
+	FromContext(ctx).WithGroup("http").Info("handled", "status", 201)
+	slog.InfoContext(WithAttrs(context.Background(), "tenant", "other"), "background")
+
+	handled, ok := logs.Find("handled")
+	require.True(t, ok)
+	assert.Equal(t, map[string]any{
+		"request_id":  "req-1",
+		"method":      "POST",
+		"tenant":      "acme",
+		"user":        "alice",
+		"http.status": int64(201),
+	}, handled.Attrs)
+
+	background, ok := logs.Find("background")
+	require.True(t, ok)
+	assert.Equal(t, map[string]any{"tenant": "other"}, background.Attrs)
+}
@@ -0,0 +1,203 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxDedupeEntries is how many tuples are tracked before those whose window ended are forgotten.
+const maxDedupeEntries = 1024
+
+// DedupeLimit lets the first Burst records of a tuple through in every Window and suppresses the rest.
+type DedupeLimit struct {
+	Burst  int
+	Window time.Duration
+}
+
+// DedupeConfig limits records by level, a level without a limit uses that of the closest lower level. Records are
+// grouped by level, message and the values of the Keys attributes.
+type DedupeConfig struct {
+	Limits map[slog.Level]DedupeLimit
+	Keys   []string
+}
+
+// limit returns the limit of the closest configured level at or below lvl.
+func (c *DedupeConfig) limit(lvl slog.Level) (DedupeLimit, bool) {
+	var (
+		limit DedupeLimit
+		from  slog.Level
+		found bool
+	)
+	for configured, l := range c.Limits {
+		if configured <= lvl && (!found || configured > from) {
+			limit, from, found = l, configured, true
+		}
+	}
+	return limit, found && limit.Burst > 0 && limit.Window > 0
+}
+
+var dedupeConfig atomic.Pointer[DedupeConfig]
+
+func init() {
+	dedupeConfig.Store(&DedupeConfig{})
+}
+
+// NewDedupeHandler creates a handler that rate limits identical records before passing them to next. Once a window in
+// which records were suppressed ends, a "suppressed N similar messages" record with the message and key attributes of
+// the suppressed records is written at their level.
+func NewDedupeHandler(next slog.Handler, config DedupeConfig) slog.Handler {
+	return newDedupeHandler(next, func() *DedupeConfig { return &config })
+}
+
+func newDedupeHandler(next slog.Handler, config func() *DedupeConfig) *dedupeHandler {
+	return &dedupeHandler{Handler: next, config: config, state: &dedupeState{entries: map[string]*dedupeEntry{}}}
+}
+
+type dedupeHandler struct {
+	slog.Handler
+	config func() *DedupeConfig
+	state  *dedupeState
+	// attrs added with WithAttrs outside any group, candidates for the key
+	attrs  []slog.Attr
+	groups bool
+}
+
+type dedupeState struct {
+	sync.Mutex
+	entries map[string]*dedupeEntry
+}
+
+// dedupeEntry counts the records of a tuple in the current window.
+type dedupeEntry struct {
+	windowEnd  time.Time
+	count      int
+	suppressed int
+	timer      *time.Timer
+}
+
+func (h *dedupeHandler) Handle(ctx context.Context, record slog.Record) error {
+	config := h.config()
+	limit, ok := config.limit(record.Level)
+	if !ok {
+		return h.Handler.Handle(ctx, record)
+	}
+
+	keyAttrs := h.keyAttrs(record, config.Keys)
+	key := tupleKey(record, keyAttrs)
+	now := time.Now()
+
+	h.state.Lock()
+	entry := h.state.entries[key]
+	if entry == nil || entry.timer == nil && !now.Before(entry.windowEnd) {
+		h.state.forgetExpired(now)
+		entry = &dedupeEntry{windowEnd: now.Add(limit.Window)}
+		h.state.entries[key] = entry
+	}
+	entry.count++
+	if entry.count <= limit.Burst {
+		h.state.Unlock()
+		return h.Handler.Handle(ctx, record)
+	}
+
+	entry.suppressed++
+	if entry.timer == nil {
+		// the summary is written by the handler the first suppressed record was logged to
+		summary := slog.NewRecord(time.Time{}, record.Level, "", 0)
+		summary.AddAttrs(slog.String("suppressed_message", record.Message))
+		summary.AddAttrs(keyAttrs...)
+		entry.timer = time.AfterFunc(entry.windowEnd.Sub(now), func() { h.summarize(key, entry, summary) })
+	}
+	h.state.Unlock()
+	return nil
+}
+
+// summarize writes the summary of the window of the entry and starts a new window.
+func (h *dedupeHandler) summarize(key string, entry *dedupeEntry, summary slog.Record) {
+	h.state.Lock()
+	suppressed := entry.suppressed
+	if h.state.entries[key] == entry {
+		delete(h.state.entries, key)
+	}
+	h.state.Unlock()
+
+	record := slog.NewRecord(time.Now(), summary.Level, fmt.Sprintf("suppressed %d similar messages", suppressed), 0)
+	summary.Attrs(func(attr slog.Attr) bool {
+		record.AddAttrs(attr)
+		return true
+	})
+	record.AddAttrs(slog.Int("suppressed", suppressed))
+	_ = h.Handler.Handle(context.Background(), record)
+}
+
+// forgetExpired removes the entries whose window ended without suppressing anything once there are too many.
+func (s *dedupeState) forgetExpired(now time.Time) {
+	if len(s.entries) < maxDedupeEntries {
+		return
+	}
+	for key, entry := range s.entries {
+		if entry.timer == nil && !now.Before(entry.windowEnd) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// keyAttrs returns the key attributes of the record and of the handler, in the order of keys.
+func (h *dedupeHandler) keyAttrs(record slog.Record, keys []string) []slog.Attr {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	found := make([]slog.Attr, len(keys))
+	set := func(attr slog.Attr) {
+		if i := slices.Index(keys, attr.Key); i >= 0 {
+			found[i] = attr
+		}
+	}
+	for _, attr := range h.attrs {
+		set(attr)
+	}
+	if !h.groups {
+		record.Attrs(func(attr slog.Attr) bool {
+			set(attr)
+			return true
+		})
+	}
+
+	return slices.DeleteFunc(found, func(attr slog.Attr) bool { return attr.Key == "" })
+}
+
+func tupleKey(record slog.Record, keyAttrs []slog.Attr) string {
+	var b strings.Builder
+	b.WriteString(record.Level.String())
+	b.WriteByte(0)
+	b.WriteString(record.Message)
+	for _, attr := range keyAttrs {
+		b.WriteByte(0)
+		b.WriteString(attr.Key)
+		b.WriteByte('=')
+		b.WriteString(attr.Value.Resolve().String())
+	}
+	return b.String()
+}
+
+func (h *dedupeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.Handler = h.Handler.WithAttrs(attrs)
+	if !h.groups {
+		clone.attrs = append(slices.Clip(h.attrs), attrs...)
+	}
+	return &clone
+}
+
+func (h *dedupeHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.Handler = h.Handler.WithGroup(name)
+	clone.groups = true
+	return &clone
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylorono/go-webservice/internal/framework/config"
+)
+
+func TestDedupeHandler(t *testing.T) {
+	recorder := &Recorder{}
+	logger := slog.New(NewDedupeHandler(&captureHandler{recorder: recorder}, DedupeConfig{
+		Limits: map[slog.Level]DedupeLimit{slog.LevelWarn: {Burst: 2, Window: 50 * time.Millisecond}},
+		Keys:   []string{"dependency"},
+	}))
+	payments := logger.With(slog.String("dependency", "payments"))
+
+	for range 5 {
+		payments.Error("call failed", slog.Int("attempt", 1))
+		logger.Error("call failed", slog.String("dependency", "ledger"))
+	}
+	logger.Info("not limited")
+	logger.Info("not limited")
+
+	assert.Len(t, recorder.Records(), 6, "two of each tuple and every info record")
+
+	require.Eventually(t, func() bool { return len(recorder.Records()) == 8 }, time.Second, 10*time.Millisecond)
+	summaries := recorder.Records()[6:]
+	for _, summary := range summaries {
+		assert.Equal(t, "suppressed 3 similar messages", summary.Message)
+		assert.Equal(t, slog.LevelError, summary.Level)
+		assert.Equal(t, "call failed", summary.Attrs["suppressed_message"])
+		assert.Equal(t, int64(3), summary.Attrs["suppressed"])
+	}
+	assert.ElementsMatch(t, []any{"payments", "ledger"}, []any{summaries[0].Attrs["dependency"], summaries[1].Attrs["dependency"]})
+
+	// a new window starts after the summary
+	payments.Error("call failed")
+	assert.Len(t, recorder.Records(), 9)
+}
+
+func TestDedupeLimits(t *testing.T) {
+	registry := &config.Configuration{Viper: viper.New()}
+	registry.Set(keyDedupe, "error=10/1s, warn=100/1m")
+
+	limits, err := dedupeLimits(registry)
+	require.NoError(t, err)
+	assert.Equal(t, map[slog.Level]DedupeLimit{
+		slog.LevelError: {Burst: 10, Window: time.Second},
+		slog.LevelWarn:  {Burst: 100, Window: time.Minute},
+	}, limits)
+
+	registry.Set(keyDedupe, map[string]any{"error": "5/10s"})
+	limits, err = dedupeLimits(registry)
+	require.NoError(t, err)
+	assert.Equal(t, map[slog.Level]DedupeLimit{slog.LevelError: {Burst: 5, Window: 10 * time.Second}}, limits)
+
+	registry.Set(keyDedupe, "error=10")
+	_, err = dedupeLimits(registry)
+	assert.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// NewFanoutHandler creates a handler passing every record to each of the handlers enabled for its level, for example a
+// text handler for everything and a JSON handler at error level writing to a separate file.
+func NewFanoutHandler(handlers ...slog.Handler) slog.Handler {
+	return fanoutHandler(handlers)
+}
+
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if handler.Enabled(ctx, record.Level) {
+			errs = append(errs, handler.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
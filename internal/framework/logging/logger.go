@@ -1,38 +1,514 @@
 package logging
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log/slog"
-	"os"
-	"testing"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/taylorono/go-webservice/internal/framework/config"
+)
+
+const (
+	keyLevel  = "log-level"
+	keyLevels = "log-levels"
+	keyFormat = "log-format"
+	keyJSON   = "log-json"
+	keySource = "log-source"
+	keyOutput = "log-output"
+
+	keyRedactHeaders  = "log-redact-headers"
+	keyRedactQuery    = "log-redact-query"
+	keyRedactFields   = "log-redact-fields"
+	keyRedactPatterns = "log-redact-patterns"
+
+	keyDumpMaxBody     = "log-dump-max-body-bytes"
+	keyDumpSampleRate  = "log-dump-sample-rate"
+	keyDumpBinaryTypes = "log-dump-binary-types"
+
+	keyRotateMaxSize    = "log-rotate-max-size"
+	keyRotateMaxAge     = "log-rotate-max-age"
+	keyRotateMaxBackups = "log-rotate-max-backups"
+	keyRotateRetention  = "log-rotate-retention"
+	keyRotateCompress   = "log-rotate-compress"
+	keyAsync            = "log-async"
+	keyAsyncBuffer      = "log-async-buffer"
+	keySinks            = "log-sinks"
+	keyDedupe           = "log-dedupe"
+	keyDedupeKeys       = "log-dedupe-keys"
+
+	keyAccessLogFormat     = "access-log-format"
+	keyAccessLogOutput     = "access-log-output"
+	keyAccessLogSampleRate = "access-log-sample-rate"
+	keyAccessLogSlow       = "access-log-slow"
+
+	// LoggerKey is the attribute naming a logger created by Logger, its level can be overridden with log-levels.
+	LoggerKey = "logger"
 )
 
 var (
-	lvl          slog.Level
-	enableJSON   bool
-	enableSource bool
+	level     = new(slog.LevelVar)
+	overrides atomic.Pointer[map[string]slog.Level]
+	redactor  atomic.Pointer[Redactor]
+
+	// mu guards the sinks the default logger and the access log write to
+	mu         sync.Mutex
+	configured bool
+	applied    sink
+	closer     io.Closer
+
+	// output and sinkOutputs are written by every logger, also those derived from a default logger since replaced
+	output      = newSwapWriter()
+	sinkOutputs = map[string]*swapWriter{}
 )
 
-func init() {
-	flag.TextVar(&lvl, "log-level", slog.LevelInfo, "log level: debug info warn error")
-	flag.BoolVar(&enableJSON, "log-json", false, "enable structured logging")
-	flag.BoolVar(&enableSource, "log-source", false, "enable logging of source file and line")
+// sink is where and how records are written, changing it replaces the default logger.
+type sink struct {
+	format string
+	source bool
+	output string
+	extra  []extraSink
+	writer writerOptions
+}
 
+func (s sink) equal(other sink) bool {
+	return s.format == other.format && s.source == other.source && s.output == other.output &&
+		slices.Equal(s.extra, other.extra) && s.writer == other.writer
 }
 
 func init() {
-	if !testing.Testing() {
-		flag.Parse()
-		if !enableJSON {
-			slog.SetLogLoggerLevel(lvl)
-			return
+	flag.String(keyLevel, "info", "log level: debug info warn error")
+	flag.String(keyLevels, "", "per logger level overrides such as web=debug,metrics=warn")
+	flag.String(keyFormat, "text", "log format: text or json")
+	flag.Bool(keyJSON, false, "deprecated, use --log-format=json")
+	flag.Bool(keySource, false, "enable logging of source file and line")
+	flag.String(keyOutput, "stdout", "log output: stdout, stderr or a file path")
+	flag.String(keyRotateMaxSize, "", "rotate log files before they grow larger than this size, such as 100MB")
+	flag.Duration(keyRotateMaxAge, 0, "rotate log files once they have been written to for this long")
+	flag.Int(keyRotateMaxBackups, 0, "number of rotated log files kept, 0 keeps all")
+	flag.Duration(keyRotateRetention, 0, "remove rotated log files older than this, 0 keeps them")
+	flag.Bool(keyRotateCompress, false, "gzip rotated log files")
+	flag.Bool(keyAsync, false, "write logs on a background goroutine, dropping the oldest records when the buffer is full")
+	flag.Int(keyAsyncBuffer, defaultAsyncBuffer, "number of records buffered by --log-async")
+	flag.String(keyDedupe, "", "per level limits of identical records such as error=10/1s,warn=100/1m, burst per window")
+	flag.String(keyDedupeKeys, "", "comma separated attributes that tell identical records apart in addition to level and message")
+	flag.String(keySinks, "", "additional outputs receiving records from a level such as errors.log=error, a list in config files")
+	flag.String(keyRedactHeaders, "", "comma separated headers masked in request dumps in addition to the defaults")
+	flag.String(keyRedactQuery, "", "comma separated query parameters masked in request dumps in addition to the defaults")
+	flag.String(keyRedactFields, "", "comma separated JSON paths such as $.user.ssn masked in dumps in addition to the defaults")
+	flag.String(keyRedactPatterns, "", "regular expression masked in dumps in addition to the defaults, a list in config files")
+	flag.Int64(keyDumpMaxBody, defaultMaxBodyBytes, "maximum bytes of a request or response body captured in dumps")
+	flag.Float64(keyDumpSampleRate, 1, "fraction of requests dumped at debug level, between 0 and 1")
+	flag.String(keyDumpBinaryTypes, "", "comma separated content type prefixes not captured in dumps in addition to the defaults")
+	flag.String(keyAccessLogFormat, "json", "access log format: "+strings.Join(AccessLogFormats, ", "))
+	flag.String(keyAccessLogOutput, "stdout", "access log output: stdout, stderr or a file path")
+	flag.Float64(keyAccessLogSampleRate, 1, "fraction of successful requests written to the access log, between 0 and 1")
+	flag.Duration(keyAccessLogSlow, defaultSlowRequest, "latency from which requests are always written to the access log, 0 to disable")
+
+	defaultRedactor, err := NewRedactor(DefaultRedactorConfig())
+	if err != nil {
+		panic(err)
+	}
+	redactor.Store(defaultRedactor)
+
+	// apply changes to the config file while running, levels change in place
+	config.OnConfigChange(func() {
+		if err := Configure(config.Registry); err != nil {
+			slog.Error("failed to reconfigure logging", slog.String("error", err.Error()))
+		}
+	})
+}
+
+// Configure applies the logging configuration from the registry. The log level and per logger levels change in place
+// for every logger, a new format or source replaces the default logger so loggers derived from the previous default keep
+// their format. A new output is written by every logger. Invalid configuration is rejected as a whole.
+func Configure(registry *config.Configuration) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(registry.GetString(keyLevel))); err != nil {
+		return fmt.Errorf("invalid %s: %w", keyLevel, err)
+	}
+
+	levels, err := loggerLevels(registry)
+	if err != nil {
+		return err
+	}
+
+	limits, err := dedupeLimits(registry)
+	if err != nil {
+		return err
+	}
+	dedupe := &DedupeConfig{Limits: limits, Keys: list(registry, keyDedupeKeys)}
+
+	next := sink{
+		format: strings.ToLower(registry.GetString(keyFormat)),
+		source: registry.GetBool(keySource),
+		output: registry.GetString(keyOutput),
+	}
+	if registry.GetBool(keyJSON) {
+		next.format = "json"
+	}
+	if next.format != "text" && next.format != "json" {
+		return fmt.Errorf("invalid %s %q: must be text or json", keyFormat, next.format)
+	}
+	if next.writer, err = writerConfig(registry); err != nil {
+		return err
+	}
+	if next.extra, err = extraSinks(registry, next.format); err != nil {
+		return err
+	}
+
+	redactions := DefaultRedactorConfig()
+	redactions.Headers = append(slices.Clip(redactions.Headers), list(registry, keyRedactHeaders)...)
+	redactions.QueryParams = append(slices.Clip(redactions.QueryParams), list(registry, keyRedactQuery)...)
+	redactions.Fields = append(slices.Clip(redactions.Fields), list(registry, keyRedactFields)...)
+	redactions.Patterns = append(slices.Clip(redactions.Patterns), patterns(registry)...)
+	configuredRedactor, err := NewRedactor(redactions)
+	if err != nil {
+		return err
+	}
+
+	dump := &dumpSettings{
+		maxBodyBytes: defaultMaxBodyBytes,
+		sampleRate:   1,
+		binaryTypes:  append(slices.Clip(DefaultBinaryContentTypes), list(registry, keyDumpBinaryTypes)...),
+	}
+	if registry.IsSet(keyDumpMaxBody) {
+		dump.maxBodyBytes = registry.GetInt64(keyDumpMaxBody)
+	}
+	if registry.IsSet(keyDumpSampleRate) {
+		dump.sampleRate = registry.GetFloat64(keyDumpSampleRate)
+	}
+	if dump.maxBodyBytes < 0 {
+		return fmt.Errorf("invalid %s: must not be negative", keyDumpMaxBody)
+	}
+	if dump.sampleRate < 0 || dump.sampleRate > 1 {
+		return fmt.Errorf("invalid %s: must be between 0 and 1", keyDumpSampleRate)
+	}
+
+	access := accessSink{
+		format: strings.ToLower(registry.GetString(keyAccessLogFormat)),
+		output: registry.GetString(keyAccessLogOutput),
+		writer: next.writer,
+	}
+	if access.format == "" {
+		access.format = "json"
+	}
+	if !slices.Contains(AccessLogFormats, access.format) {
+		return fmt.Errorf("invalid %s %q: must be one of %s", keyAccessLogFormat, access.format, strings.Join(AccessLogFormats, ", "))
+	}
+	accessSampleRate, accessSlow := 1.0, defaultSlowRequest
+	if registry.IsSet(keyAccessLogSampleRate) {
+		accessSampleRate = registry.GetFloat64(keyAccessLogSampleRate)
+	}
+	if registry.IsSet(keyAccessLogSlow) {
+		accessSlow = registry.GetDuration(keyAccessLogSlow)
+	}
+	if accessSampleRate < 0 || accessSampleRate > 1 {
+		return fmt.Errorf("invalid %s: must be between 0 and 1", keyAccessLogSampleRate)
+	}
+	if accessSlow < 0 {
+		return fmt.Errorf("invalid %s: must not be negative", keyAccessLogSlow)
+	}
+
+	if err := applySinks(next, access, accessSampleRate, accessSlow); err != nil {
+		return err
+	}
+	redactor.Store(configuredRedactor)
+	dumpConfig.Store(dump)
+	dedupeConfig.Store(dedupe)
+
+	// the configuration takes precedence over temporary changes made on the debug port
+	temporary.cancel()
+	level.Set(lvl)
+	overrides.Store(&levels)
+	return nil
+}
+
+// loggerLevels reads log-levels either as a map or as a comma separated list of name=level pairs.
+func loggerLevels(registry *config.Configuration) (map[string]slog.Level, error) {
+	raw := registry.GetStringMapString(keyLevels)
+	if len(raw) == 0 {
+		raw = map[string]string{}
+		for _, pair := range strings.Split(registry.GetString(keyLevels), ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid %s entry %q: expected name=level", keyLevels, pair)
+			}
+			raw[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+
+	levels := make(map[string]slog.Level, len(raw))
+	for name, value := range raw {
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(value)); err != nil {
+			return nil, fmt.Errorf("invalid %s level for %q: %w", keyLevels, name, err)
+		}
+		levels[name] = lvl
+	}
+	return levels, nil
+}
+
+// dedupeLimits reads log-dedupe either as a map or as a comma separated list of level=burst/window pairs.
+func dedupeLimits(registry *config.Configuration) (map[slog.Level]DedupeLimit, error) {
+	raw := registry.GetStringMapString(keyDedupe)
+	if len(raw) == 0 {
+		raw = map[string]string{}
+		for _, pair := range strings.Split(registry.GetString(keyDedupe), ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid %s entry %q: expected level=burst/window", keyDedupe, pair)
+			}
+			raw[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+
+	limits := make(map[slog.Level]DedupeLimit, len(raw))
+	for name, value := range raw {
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(name)); err != nil {
+			return nil, fmt.Errorf("invalid %s level %q: %w", keyDedupe, name, err)
+		}
+
+		burst, window, ok := strings.Cut(value, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s limit %q for %s: expected burst/window", keyDedupe, value, name)
+		}
+		var (
+			limit DedupeLimit
+			err   error
+		)
+		if limit.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || limit.Burst < 0 {
+			return nil, fmt.Errorf("invalid %s burst %q for %s: must be a non negative number", keyDedupe, burst, name)
+		}
+		if limit.Window, err = time.ParseDuration(strings.TrimSpace(window)); err != nil || limit.Window <= 0 {
+			return nil, fmt.Errorf("invalid %s window %q for %s: must be a positive duration", keyDedupe, window, name)
 		}
+		limits[lvl] = limit
+	}
+	return limits, nil
+}
+
+// list reads a key that is either a list in a config file or a comma separated string.
+func list(registry *config.Configuration, key string) []string {
+	var values []string
+	for _, value := range registry.GetStringSlice(key) {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+// patterns reads the redaction patterns, a list or a single pattern as patterns may contain commas.
+func patterns(registry *config.Configuration) []string {
+	if value, ok := registry.Get(keyRedactPatterns).(string); ok {
+		if value == "" {
+			return nil
+		}
+		return []string{value}
+	}
+	return registry.GetStringSlice(keyRedactPatterns)
+}
+
+func currentRedactor() *Redactor {
+	return redactor.Load()
+}
+
+// applySinks replaces the default logger and the access log. Every output is opened before either is replaced, so an
+// output that cannot be opened leaves both as they were.
+func applySinks(next sink, access accessSink, accessSampleRate float64, accessSlow time.Duration) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	applyLog, outputs, err := openSink(next)
+	if err != nil {
+		return err
+	}
+	applyAccess, err := openAccessLog(access, accessSampleRate, accessSlow)
+	if err != nil {
+		_ = outputs.Close()
+		return err
+	}
+
+	applyLog()
+	applyAccess()
+	return nil
+}
+
+// openSink opens the outputs of the sink and returns the function replacing the default logger with it, or the closer of
+// the outputs when it is not applied. Loggers derived from the previous default write to the new outputs, records for a
+// sink that is no longer configured are discarded. Nothing is opened when the sink did not change.
+func openSink(next sink) (apply func(), outputs io.Closer, err error) {
+	if configured && next.equal(applied) {
+		return func() {}, closeAll{}, nil
+	}
+
+	w, c, err := open(keyOutput, next.output, next.writer)
+	if err != nil {
+		return nil, nil, err
+	}
+	closers := closeAll{c}
+	// the log output follows the log level and per logger levels, the sinks their own level even below those
+	handlers := []slog.Handler{&levelHandler{Handler: formatHandler(output, next.format == "json", next.source, slog.Level(math.MinInt))}}
+
+	opened := map[string]io.Writer{}
+	swaps := map[string]*swapWriter{}
+	for _, extra := range next.extra {
+		if _, ok := opened[extra.output]; !ok {
+			w, c, err := open(keySinks, extra.output, next.writer)
+			if err != nil {
+				_ = closers.Close()
+				return nil, nil, err
+			}
+			closers = append(closers, c)
+			swap, ok := sinkOutputs[extra.output]
+			if !ok {
+				swap = newSwapWriter()
+			}
+			opened[extra.output], swaps[extra.output] = w, swap
+		}
+		handlers = append(handlers, formatHandler(swaps[extra.output], extra.format == "json", next.source, extra.level))
+	}
+
+	handler := handlers[0]
+	if len(handlers) > 1 {
+		handler = NewFanoutHandler(handlers...)
+	}
+
+	return func() {
+		output.swap(w)
+		for name, swap := range sinkOutputs {
+			if _, ok := swaps[name]; !ok {
+				swap.swap(io.Discard)
+			}
+		}
+		for name, swap := range swaps {
+			swap.swap(opened[name])
+		}
+		sinkOutputs = swaps
+		slog.SetDefault(slog.New(newHandler(handler)))
+
+		if closer != nil {
+			_ = closer.Close()
+		}
+		applied, closer, configured = next, closers, true
+	}, closers, nil
+}
+
+// swapWriter writes to an output that is replaced when the configuration changes. Handlers created before a change
+// write to the new output rather than to the previous one, which is closed once swapped out.
+type swapWriter struct {
+	mu sync.RWMutex
+	w  io.Writer
+}
+
+func newSwapWriter() *swapWriter {
+	return &swapWriter{w: io.Discard}
+}
+
+func (s *swapWriter) Write(p []byte) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.w.Write(p)
+}
+
+// swap replaces the output, returning once writes to the previous one have completed so it can be closed.
+func (s *swapWriter) swap(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w = w
+}
+
+// NewHandler creates the handler stack of the default logger writing text or JSON to w. It adds the request attributes
+// of the context, filters records by the configured level and per logger levels and suppresses duplicates as configured
+// with log-dedupe.
+func NewHandler(w io.Writer, json, source bool) slog.Handler {
+	// levels are decided by the level handler, the writer accepts everything it is given
+	return newHandler(&levelHandler{Handler: formatHandler(w, json, source, slog.Level(math.MinInt))})
+}
+
+// newHandler wraps the handler writing records with the context and duplicate suppression handlers. The handler filters
+// the levels of each output itself, so a sink can receive records below the log level.
+func newHandler(handler slog.Handler) slog.Handler {
+	return NewContextHandler(newDedupeHandler(handler, dedupeConfig.Load))
+}
 
-		opts := &slog.HandlerOptions{Level: lvl, AddSource: enableSource}
-		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, opts)))
+// formatHandler writes the records from the level as text or JSON.
+func formatHandler(w io.Writer, json, source bool, lvl slog.Level) slog.Handler {
+	opts := &slog.HandlerOptions{Level: lvl, AddSource: source}
+	if json {
+		return slog.NewJSONHandler(w, opts)
 	}
+	return slog.NewTextHandler(w, opts)
 }
 
+// Level returns the configured log level.
 func Level() slog.Level {
-	return lvl
+	return level.Level()
+}
+
+// Logger returns the default logger named name. Its level can be overridden with log-levels, an override for "web" also
+// applies to "web.tls" unless that has its own.
+func Logger(name string) *slog.Logger {
+	return slog.Default().With(slog.String(LoggerKey, name))
+}
+
+// levelFor returns the level of the named logger, the override for the longest matching name or the configured level.
+func levelFor(name string) slog.Level {
+	levels := overrides.Load()
+	if name == "" || levels == nil {
+		return level.Level()
+	}
+
+	for candidate := name; ; {
+		if lvl, ok := (*levels)[candidate]; ok {
+			return lvl
+		}
+		i := strings.LastIndex(candidate, ".")
+		if i < 0 {
+			return level.Level()
+		}
+		candidate = candidate[:i]
+	}
+}
+
+// levelHandler filters records by the level of the logger they are written to, letting forced request dumps through.
+type levelHandler struct {
+	slog.Handler
+	logger string
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
+	return lvl >= levelFor(h.logger) || forcedDump(ctx)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	logger := h.logger
+	for _, attr := range attrs {
+		if attr.Key == LoggerKey {
+			logger = attr.Value.String()
+		}
+	}
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), logger: logger}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), logger: h.logger}
 }
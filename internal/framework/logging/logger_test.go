@@ -0,0 +1,191 @@
+package logging
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylorono/go-webservice/internal/framework/config"
+)
+
+func TestConfigure(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	output := filepath.Join(t.TempDir(), "app.log")
+	registry := &config.Configuration{Viper: viper.New()}
+	registry.Set(keyLevel, "warn")
+	registry.Set(keyLevels, "web=debug,web.tls=error")
+	registry.Set(keyFormat, "json")
+	registry.Set(keyOutput, output)
+	registry.Set(keyRedactHeaders, "X-Tenant, X-Session")
+	registry.Set(keyAccessLogFormat, "combined")
+	registry.Set(keyAccessLogOutput, filepath.Join(t.TempDir(), "access.log"))
+	registry.Set(keyAccessLogSlow, "250ms")
+	require.NoError(t, Configure(registry))
+
+	access := accessConfig.Load()
+	assert.NotNil(t, access.write)
+	assert.Equal(t, 250*time.Millisecond, access.slow)
+
+	redacted := currentRedactor().Header(http.Header{"X-Session": {"s"}, "Authorization": {"a"}, "Accept": {"*/*"}})
+	assert.Equal(t, http.Header{"X-Session": {Mask}, "Authorization": {Mask}, "Accept": {"*/*"}}, redacted)
+
+	ctx := context.Background()
+	assert.False(t, slog.Default().Enabled(ctx, slog.LevelInfo))
+	assert.True(t, Logger("web").Enabled(ctx, slog.LevelDebug))
+	assert.True(t, Logger("web.codec").Enabled(ctx, slog.LevelDebug))
+	assert.False(t, Logger("web.tls").Enabled(ctx, slog.LevelWarn))
+	assert.False(t, Logger("metrics").Enabled(ctx, slog.LevelInfo))
+
+	// levels change in place for loggers created earlier
+	metrics := Logger("metrics")
+	registry.Set(keyLevel, "info")
+	require.NoError(t, Configure(registry))
+	assert.True(t, metrics.Enabled(ctx, slog.LevelInfo))
+
+	slog.Info("written", "n", 1)
+	b, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"msg":"written"`)
+
+	registry.Set(keyLevel, "loud")
+	assert.Error(t, Configure(registry))
+	assert.Equal(t, slog.LevelInfo, Level(), "invalid configuration is not applied")
+
+	registry.Set(keyOutput, "stdout")
+	registry.Set(keyAccessLogFormat, "off")
+	registry.Set(keyLevel, "info")
+	require.NoError(t, Configure(registry))
+}
+
+func TestConfigure_OutputChange(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")
+	errors := filepath.Join(dir, "errors.log")
+	registry := &config.Configuration{Viper: viper.New()}
+	registry.Set(keyLevel, "info")
+	registry.Set(keyFormat, "text")
+	registry.Set(keyOutput, first)
+	registry.Set(keySinks, errors+"=error")
+	registry.Set(keyAsync, true)
+	registry.Set(keyAccessLogFormat, "logfmt")
+	registry.Set(keyAccessLogOutput, first)
+	require.NoError(t, Configure(registry))
+
+	logger := Logger("web")
+	inFlight := accessConfig.Load()
+	registry.Set(keyOutput, second)
+	registry.Set(keySinks, "")
+	registry.Set(keyAccessLogOutput, second)
+	require.NoError(t, Configure(registry))
+
+	logger.Error("after")
+	inFlight.write(&accessEntry{time: time.Now(), method: http.MethodGet, path: "/items"})
+
+	// closing the outputs writes the pending records
+	registry.Set(keyOutput, "stdout")
+	registry.Set(keyAccessLogFormat, "off")
+	registry.Set(keyAsync, false)
+	require.NoError(t, Configure(registry))
+
+	written, err := os.ReadFile(second)
+	require.NoError(t, err)
+	assert.Contains(t, string(written), "msg=after", "loggers created earlier write to the new output")
+	assert.Contains(t, string(written), "msg=access", "requests started earlier write to the new access log output")
+	for _, path := range []string{first, errors} {
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(b), "after", "replaced outputs are no longer written")
+	}
+}
+
+func TestConfigure_InvalidAccessLogOutput(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	assert.Equal(t, "json", flag.Lookup(keyAccessLogFormat).DefValue, "every request is logged unless turned off")
+
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")
+	registry := &config.Configuration{Viper: viper.New()}
+	registry.Set(keyLevel, "info")
+	registry.Set(keyFormat, "text")
+	registry.Set(keyOutput, first)
+	require.NoError(t, Configure(registry))
+	access := accessConfig.Load()
+
+	registry.Set(keyFormat, "json")
+	registry.Set(keyOutput, second)
+	registry.Set(keyAccessLogFormat, "logfmt")
+	registry.Set(keyAccessLogOutput, filepath.Join(dir, "missing", "access.log"))
+	assert.ErrorContains(t, Configure(registry), keyAccessLogOutput)
+
+	slog.Info("kept")
+	written, err := os.ReadFile(first)
+	require.NoError(t, err)
+	assert.Contains(t, string(written), "msg=kept", "the log output is not replaced when the access log cannot be opened")
+	assert.Same(t, access, accessConfig.Load(), "the access log is not replaced either")
+
+	registry.Set(keyOutput, "stdout")
+	registry.Set(keyAccessLogFormat, "off")
+	require.NoError(t, Configure(registry))
+}
+
+func TestConfigure_Sinks(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	dir := t.TempDir()
+	output, errors, trace := filepath.Join(dir, "app.log"), filepath.Join(dir, "errors.log"), filepath.Join(dir, "trace.log")
+	registry := &config.Configuration{Viper: viper.New()}
+	registry.Set(keyLevel, "info")
+	registry.Set(keyFormat, "text")
+	registry.Set(keyOutput, output)
+	registry.Set(keySinks, []map[string]any{
+		{"output": errors, "level": "error", "format": "json"},
+		{"output": trace, "level": "debug"},
+	})
+	registry.Set(keyAsync, true)
+	require.NoError(t, Configure(registry))
+
+	slog.Debug("tracing")
+	slog.Info("started")
+	slog.Error("failed")
+
+	// closing the outputs writes the pending records
+	registry.Set(keyOutput, "stdout")
+	registry.Set(keySinks, "")
+	registry.Set(keyAsync, false)
+	require.NoError(t, Configure(registry))
+
+	all, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(all), "msg=started")
+	assert.Contains(t, string(all), "msg=failed")
+	assert.NotContains(t, string(all), "tracing", "the log output keeps the log level")
+
+	traced, err := os.ReadFile(trace)
+	require.NoError(t, err)
+	assert.Contains(t, string(traced), "msg=tracing", "sinks receive records below the log level")
+	assert.Contains(t, string(traced), "msg=started")
+
+	errs, err := os.ReadFile(errors)
+	require.NoError(t, err)
+	assert.NotContains(t, string(errs), "started")
+	assert.Contains(t, string(errs), `"msg":"failed"`)
+
+	registry.Set(keySinks, "errors.log")
+	assert.Error(t, Configure(registry))
+}
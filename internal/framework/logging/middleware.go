@@ -1,69 +1,199 @@
 package logging
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"strings"
+	"sync/atomic"
 )
 
-// HttpLoggingMiddleware creates a middleware that logs the full request and response
+// defaultMaxBodyBytes is how much of a body dumps capture unless configured otherwise.
+const defaultMaxBodyBytes = 64 << 10
+
+// DefaultBinaryContentTypes are media type prefixes whose bodies are never captured in dumps.
+var DefaultBinaryContentTypes = []string{
+	"image/", "audio/", "video/", "font/", "multipart/",
+	"application/octet-stream", "application/zip", "application/gzip", "application/pdf",
+	"application/x-protobuf", "application/protobuf", "application/msgpack", "application/x-msgpack",
+	"application/vnd.msgpack", "application/cbor", "application/grpc",
+}
+
+// dumpSettings bound the cost of request dumps.
+type dumpSettings struct {
+	maxBodyBytes int64
+	sampleRate   float64
+	binaryTypes  []string
+}
+
+var dumpConfig atomic.Pointer[dumpSettings]
+
+func init() {
+	dumpConfig.Store(&dumpSettings{maxBodyBytes: defaultMaxBodyBytes, sampleRate: 1, binaryTypes: DefaultBinaryContentTypes})
+}
+
+// HttpLoggingMiddleware creates a middleware that logs the full request and response. Requests are dumped at debug level
+// while debug logging is enabled, sampled by log-dump-sample-rate, and at info level whatever the log level while a dump
+// rule enabled on the debug port matches them. Other requests pass through untouched. Bodies are captured up to log-dump-max-body-bytes
+// without delaying the handler, binary bodies are left out, and the response writer keeps supporting http.Flusher,
+// http.Hijacker and io.ReaderFrom.
 func HttpLoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logRequest(r)
+		lvl, forced, ok := dumpLevel(r)
+		if !ok {
+			next(w, r)
+			return
+		}
 
-		recorder := &responseRecorder{ResponseWriter: w, body: &bytes.Buffer{}}
+		// only the dump records of a forced dump bypass the level, the handler keeps logging at the configured one
+		handled := r.Context()
+		if forced {
+			r = r.WithContext(context.WithValue(handled, forcedDumpKey{}, true))
+		}
 
-		next(recorder, r)
+		settings := dumpConfig.Load()
+		logRequest(r, lvl, settings)
 
-		logResponse(recorder)
+		recorder := newResponseRecorder(w, settings)
+
+		next(recorder, r.WithContext(handled))
+
+		logResponse(r, recorder, lvl)
 	}
 }
 
-func logRequest(r *http.Request) {
-	requestDump, err := httputil.DumpRequest(r, true)
-	if err != nil {
-		slog.Error("failed to dump request", slog.String("error", err.Error()))
-		return
+type forcedDumpKey struct{}
+
+// forcedDump reports whether the context is that of a dump forced by a dump rule, written whatever the log level.
+func forcedDump(ctx context.Context) bool {
+	forced, _ := ctx.Value(forcedDumpKey{}).(bool)
+	return forced
+}
+
+// dumpLevel returns the level to dump the request at, whether a dump rule forces the dump and whether it should be
+// dumped at all.
+func dumpLevel(r *http.Request) (lvl slog.Level, forced, ok bool) {
+	if slog.Default().Enabled(r.Context(), slog.LevelDebug) && sampled(dumpConfig.Load().sampleRate) {
+		return slog.LevelDebug, false, true
 	}
+	if rate, ok := dumps.match(r); ok && sampled(rate) {
+		return slog.LevelInfo, true, true
+	}
+	return 0, false, false
+}
 
-	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
-		slog.Debug("HTTP Request", slog.String("dump", string(requestDump)))
-		return
+func sampled(rate float64) bool {
+	return rate >= 1 || rand.Float64() < rate
+}
+
+func (s *dumpSettings) binary(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range s.binaryTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
 	}
+	return false
+}
 
-	// We need to parse the dump to separate headers and body
-	parts := strings.SplitN(string(requestDump), "\r\n\r\n", 2)
-	if len(parts) != 2 {
-		slog.Debug("HTTP Request", slog.String("dump", string(requestDump)))
-		return
+func logRequest(r *http.Request, lvl slog.Level, settings *dumpSettings) {
+	redactor := currentRedactor()
+	contentType := r.Header.Get("Content-Type")
+
+	// capture at most the limit of bodies of known length and replay them to the handler, a failed read is replayed too.
+	// Bodies of unknown length may be streamed by the client, so reading them ahead of the handler could block it.
+	var (
+		body []byte
+		note string
+	)
+	switch {
+	case r.Body == nil || r.Body == http.NoBody:
+	case settings.binary(contentType):
+		note = fmt.Sprintf("[%s body of %d bytes not captured]", contentType, r.ContentLength)
+	case r.ContentLength < 0:
+		note = "[streamed body not captured]"
+	default:
+		var err error
+		body, err = io.ReadAll(io.LimitReader(r.Body, settings.maxBodyBytes))
+		r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to dump request", slog.String("error", err.Error()))
+			return
+		}
+		if truncated := r.ContentLength - int64(len(body)); truncated > 0 {
+			note = fmt.Sprintf("[truncated %d bytes]", truncated)
+		}
 	}
 
-	if indented, ok := prettyJSON([]byte(parts[1])); ok {
-		slog.Debug("HTTP Request", slog.String("headers", parts[0]), slog.String("body", indented))
+	redacted := r.Clone(r.Context())
+	redacted.Header = redactor.Header(r.Header)
+	redacted.URL.RawQuery = redactor.Query(r.URL.RawQuery)
+	redacted.RequestURI = ""
+	redacted.Body = nil
+	head, err := httputil.DumpRequest(redacted, false)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to dump request", slog.String("error", err.Error()))
 		return
 	}
+	headers := strings.TrimRight(string(head), "\r\n")
+
+	body = redactor.Body(contentType, body)
+
+	if note == "" && strings.HasPrefix(contentType, "application/json") {
+		if indented, ok := prettyJSON(body); ok {
+			slog.Log(r.Context(), lvl, "HTTP Request", slog.String("headers", headers), slog.String("body", indented))
+			return
+		}
+	}
 
-	slog.Debug("HTTP Request", slog.String("dump", string(requestDump)))
+	slog.Log(r.Context(), lvl, "HTTP Request", slog.String("dump", headers+"\r\n\r\n"+string(body)+note))
 }
 
-func logResponse(recorder *responseRecorder) {
+func logResponse(r *http.Request, recorder *responseRecorder, lvl slog.Level) {
+	if recorder.hijacked {
+		slog.Log(r.Context(), lvl, "HTTP Response", slog.String("body", "[connection hijacked]"))
+		return
+	}
+
+	redactor := currentRedactor()
 	contentType := recorder.Header().Get("Content-Type")
-	bodyStr := recorder.body.String()
+	body := redactor.Body(contentType, recorder.body.Bytes())
 
-	if !strings.HasPrefix(contentType, "application/json") {
-		slog.Debug("HTTP Response", slog.Int("status", recorder.statusCode), slog.String("body", bodyStr))
-		return
+	var note string
+	switch {
+	case recorder.skipped:
+		note = fmt.Sprintf("[%s body of %d bytes not captured]", contentType, recorder.size)
+	case recorder.size > int64(recorder.body.Len()):
+		note = fmt.Sprintf("[truncated %d bytes]", recorder.size-int64(recorder.body.Len()))
 	}
 
-	if indented, ok := prettyJSON(recorder.body.Bytes()); ok {
-		slog.Debug("HTTP Response", slog.Int("status", recorder.statusCode), slog.String("body", indented))
-		return
+	attrs := []any{
+		slog.Int("status", recorder.statusCode),
+		slog.Any("headers", redactor.Header(recorder.Header())),
+	}
+
+	if note == "" && strings.HasPrefix(contentType, "application/json") {
+		if indented, ok := prettyJSON(body); ok {
+			slog.Log(r.Context(), lvl, "HTTP Response", append(attrs, slog.String("body", indented))...)
+			return
+		}
 	}
 
-	slog.Debug("HTTP Response", slog.Int("status", recorder.statusCode), slog.String("body", bodyStr))
+	slog.Log(r.Context(), lvl, "HTTP Response", append(attrs, slog.String("body", string(body)+note))...)
+}
+
+// readCloser replays a read body while closing the original.
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
 
 func prettyJSON(b []byte) (string, bool) {
@@ -74,18 +204,92 @@ func prettyJSON(b []byte) (string, bool) {
 	return indented.String(), true
 }
 
+// responseRecorder captures the status and the start of the body while passing everything through to the client.
 type responseRecorder struct {
 	http.ResponseWriter
-	statusCode int
-	body       *bytes.Buffer
+	settings    *dumpSettings
+	statusCode  int
+	wroteHeader bool
+	body        *bytes.Buffer
+	size        int64
+	skipped     bool
+	hijacked    bool
+}
+
+func newResponseRecorder(w http.ResponseWriter, settings *dumpSettings) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, settings: settings, statusCode: http.StatusOK, body: &bytes.Buffer{}}
 }
 
 func (r *responseRecorder) WriteHeader(statusCode int) {
-	r.statusCode = statusCode
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		// informational responses are followed by the final one
+		r.wroteHeader = statusCode >= 200
+		r.skipped = r.settings.binary(r.Header().Get("Content-Type"))
+	}
 	r.ResponseWriter.WriteHeader(statusCode)
 }
 
 func (r *responseRecorder) Write(b []byte) (int, error) {
-	r.body.Write(b)
-	return r.ResponseWriter.Write(b)
+	if !r.wroteHeader && r.Header().Get("Content-Type") == "" {
+		// net/http sniffs the content type of the first write, do the same to recognise binary bodies
+		r.wroteHeader = true
+		r.skipped = r.settings.binary(http.DetectContentType(b))
+	}
+	r.writeHeader()
+	r.capture(b)
+	n, err := r.ResponseWriter.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+// writeHeader records the implicit 200 OK of a write without a status, once the content type is final.
+func (r *responseRecorder) writeHeader() {
+	if !r.wroteHeader {
+		r.wroteHeader = true
+		r.skipped = r.settings.binary(r.Header().Get("Content-Type"))
+	}
+}
+
+func (r *responseRecorder) capture(b []byte) {
+	if r.skipped {
+		return
+	}
+	if remaining := r.settings.maxBodyBytes - int64(r.body.Len()); remaining > 0 {
+		r.body.Write(b[:min(int64(len(b)), remaining)])
+	}
+}
+
+// ReadFrom lets the underlying writer copy directly, for example with sendfile, once nothing more is captured.
+func (r *responseRecorder) ReadFrom(src io.Reader) (int64, error) {
+	r.writeHeader()
+	if readerFrom, ok := r.ResponseWriter.(io.ReaderFrom); ok && (r.skipped || int64(r.body.Len()) >= r.settings.maxBodyBytes) {
+		n, err := readerFrom.ReadFrom(src)
+		r.size += n
+		return n, err
+	}
+	return io.Copy(writerOnly{r}, src)
+}
+
+func (r *responseRecorder) Flush() {
+	r.writeHeader()
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// writerOnly hides ReadFrom so io.Copy does not call it recursively.
+type writerOnly struct {
+	io.Writer
 }
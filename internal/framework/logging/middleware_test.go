@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withDumpSettings(t *testing.T, settings dumpSettings) {
+	t.Helper()
+
+	previous := dumpConfig.Load()
+	dumpConfig.Store(&settings)
+	t.Cleanup(func() { dumpConfig.Store(previous) })
+}
+
+func TestHttpLoggingMiddleware_Truncates(t *testing.T) {
+	logs := Capture(t)
+	withDumpSettings(t, dumpSettings{maxBodyBytes: 4, sampleRate: 1})
+
+	var received string
+	handler := HttpLoggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		_, _ = w.Write([]byte("response body"))
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("request body")))
+
+	assert.Equal(t, "request body", received, "the handler reads the whole body")
+
+	request, ok := logs.Find("HTTP Request")
+	require.True(t, ok)
+	assert.True(t, strings.HasSuffix(request.Attrs["dump"].(string), "requ[truncated 8 bytes]"))
+
+	response, ok := logs.Find("HTTP Response")
+	require.True(t, ok)
+	assert.Equal(t, "resp[truncated 9 bytes]", response.Attrs["body"])
+}
+
+func TestHttpLoggingMiddleware_SkipsBinary(t *testing.T) {
+	logs := Capture(t)
+	withDumpSettings(t, dumpSettings{maxBodyBytes: 1024, sampleRate: 1, binaryTypes: DefaultBinaryContentTypes})
+
+	handler := HttpLoggingMiddleware(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG"))
+	})
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("\x00\x01"))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	handler(httptest.NewRecorder(), req)
+
+	request, ok := logs.Find("HTTP Request")
+	require.True(t, ok)
+	assert.Contains(t, request.Attrs["dump"], "[application/octet-stream body of 2 bytes not captured]")
+
+	response, ok := logs.Find("HTTP Response")
+	require.True(t, ok)
+	assert.Equal(t, "[image/png body of 4 bytes not captured]", response.Attrs["body"])
+}
+
+func TestHttpLoggingMiddleware_Sampling(t *testing.T) {
+	logs := Capture(t)
+	withDumpSettings(t, dumpSettings{maxBodyBytes: 1024, sampleRate: 0})
+
+	handler := HttpLoggingMiddleware(func(w http.ResponseWriter, _ *http.Request) {})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Empty(t, logs.Records())
+}
+
+func TestHttpLoggingMiddleware_Streaming(t *testing.T) {
+	logs := Capture(t)
+	withDumpSettings(t, dumpSettings{maxBodyBytes: 1024, sampleRate: 1})
+
+	// the client writes the body only after the handler has answered, reading it ahead would block forever
+	body, writer := io.Pipe()
+	handler := HttpLoggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first"))
+		http.NewResponseController(w).Flush()
+
+		go func() {
+			_, _ = writer.Write([]byte("data"))
+			_ = writer.Close()
+		}()
+		received, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(received)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "firstdata", rec.Body.String())
+
+	request, ok := logs.Find("HTTP Request")
+	require.True(t, ok)
+	assert.Contains(t, request.Attrs["dump"], "[streamed body not captured]")
+}
+
+func TestHttpLoggingMiddleware_Flusher(t *testing.T) {
+	Capture(t)
+
+	handler := HttpLoggingMiddleware(func(w http.ResponseWriter, _ *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		require.True(t, ok)
+		flusher.Flush()
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.True(t, rec.Flushed)
+}
+
+func TestHttpLoggingMiddleware_ReaderFrom(t *testing.T) {
+	logs := Capture(t)
+	withDumpSettings(t, dumpSettings{maxBodyBytes: 3, sampleRate: 1})
+
+	handler := HttpLoggingMiddleware(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.(io.ReaderFrom).ReadFrom(strings.NewReader("streamed"))
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "streamed", rec.Body.String())
+	response, ok := logs.Find("HTTP Response")
+	require.True(t, ok)
+	assert.Equal(t, "str[truncated 5 bytes]", response.Attrs["body"])
+}
@@ -0,0 +1,300 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces redacted values in request and response dumps.
+const Mask = "[REDACTED]"
+
+// CardNumberPattern matches card numbers of 13 to 19 digits, optionally grouped by spaces or dashes. Its matches are only
+// masked when the digits pass the Luhn check, so other long numbers such as order or tracking numbers are kept.
+const CardNumberPattern = `\b(?:\d[ -]?){12,18}\d\b`
+
+var (
+	// DefaultRedactedHeaders carry credentials or session state.
+	DefaultRedactedHeaders = []string{
+		"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token", "X-Csrf-Token",
+	}
+
+	// DefaultRedactedFields mask credentials and personal data at any depth of a JSON body.
+	DefaultRedactedFields = []string{
+		"$..password", "$..secret", "$..token", "$..access_token", "$..refresh_token", "$..client_secret",
+		"$..api_key", "$..apiKey", "$..ssn", "$..card_number", "$..cardNumber", "$..cvv",
+	}
+
+	// DefaultRedactedQueryParams carry credentials in URLs.
+	DefaultRedactedQueryParams = []string{
+		"access_token", "token", "api_key", "apikey", "key", "password", "secret", "signature", "code",
+	}
+
+	// DefaultRedactedPatterns match card numbers and email addresses anywhere in a body.
+	DefaultRedactedPatterns = []string{
+		CardNumberPattern,
+		`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	}
+)
+
+// Redactor masks sensitive headers, query parameters, JSON fields and text patterns in dumps.
+type Redactor struct {
+	headers  map[string]bool
+	query    map[string]bool
+	fields   [][]pathStep
+	keys     []*regexp.Regexp
+	patterns []redactPattern
+}
+
+// redactPattern masks the matches of a pattern that are valid, or every match when valid is nil.
+type redactPattern struct {
+	*regexp.Regexp
+	valid func(match []byte) bool
+}
+
+// RedactorConfig lists what a Redactor masks. Header and query parameter names are case insensitive. Fields are JSON paths
+// such as "$.user.ssn", "$.items[*].card" or "$..password", where ".." matches at any depth.
+type RedactorConfig struct {
+	Headers     []string
+	QueryParams []string
+	Fields      []string
+	Patterns    []string
+}
+
+// DefaultRedactorConfig returns the safe defaults, configured redactions are added to these.
+func DefaultRedactorConfig() RedactorConfig {
+	return RedactorConfig{
+		Headers:     DefaultRedactedHeaders,
+		QueryParams: DefaultRedactedQueryParams,
+		Fields:      DefaultRedactedFields,
+		Patterns:    DefaultRedactedPatterns,
+	}
+}
+
+// NewRedactor compiles the config, failing on invalid field paths or patterns.
+func NewRedactor(config RedactorConfig) (*Redactor, error) {
+	r := &Redactor{headers: map[string]bool{}, query: map[string]bool{}}
+
+	for _, header := range config.Headers {
+		r.headers[http.CanonicalHeaderKey(strings.TrimSpace(header))] = true
+	}
+	for _, param := range config.QueryParams {
+		r.query[strings.ToLower(strings.TrimSpace(param))] = true
+	}
+	for _, field := range config.Fields {
+		path, err := parsePath(field)
+		if err != nil {
+			return nil, err
+		}
+		r.fields = append(r.fields, path)
+
+		// bodies that cannot be parsed, such as truncated ones, mask the last key of the path wherever it appears
+		if last := path[len(path)-1]; !last.any {
+			r.keys = append(r.keys, regexp.MustCompile(`("`+regexp.QuoteMeta(last.key)+`"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`))
+		}
+	}
+	for _, pattern := range config.Patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		p := redactPattern{Regexp: compiled}
+		if pattern == CardNumberPattern {
+			p.valid = luhn
+		}
+		r.patterns = append(r.patterns, p)
+	}
+
+	return r, nil
+}
+
+// Header returns a copy of the header with the values of redacted headers masked.
+func (r *Redactor) Header(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name, values := range redacted {
+		if r.headers[http.CanonicalHeaderKey(name)] {
+			for i := range values {
+				values[i] = Mask
+			}
+		}
+	}
+	return redacted
+}
+
+// Query returns the raw query with the values of redacted parameters masked.
+func (r *Redactor) Query(rawQuery string) string {
+	if rawQuery == "" || len(r.query) == 0 {
+		return rawQuery
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Mask
+	}
+	for name, vals := range values {
+		if r.query[strings.ToLower(name)] {
+			for i := range vals {
+				vals[i] = Mask
+			}
+		}
+	}
+	return values.Encode()
+}
+
+// Body returns the body with redacted JSON fields, form values and text patterns masked. JSON bodies that cannot be
+// parsed, such as truncated ones, have the last key of every field path masked wherever it appears.
+func (r *Redactor) Body(contentType string, body []byte) []byte {
+	switch {
+	case strings.HasPrefix(contentType, "application/json") || strings.HasSuffix(strings.Split(contentType, ";")[0], "+json"):
+		body = r.json(body)
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		body = []byte(r.Query(string(body)))
+	}
+
+	for _, pattern := range r.patterns {
+		if pattern.valid == nil {
+			body = pattern.ReplaceAll(body, []byte(Mask))
+			continue
+		}
+		body = pattern.ReplaceAllFunc(body, func(match []byte) []byte {
+			if pattern.valid(match) {
+				return []byte(Mask)
+			}
+			return match
+		})
+	}
+	return body
+}
+
+// luhn reports whether the digits of the match, ignoring separators, have a valid Luhn check digit.
+func luhn(match []byte) bool {
+	sum, double := 0, false
+	for i := len(match) - 1; i >= 0; i-- {
+		if match[i] < '0' || match[i] > '9' {
+			continue
+		}
+		digit := int(match[i] - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func (r *Redactor) json(body []byte) []byte {
+	if len(r.fields) == 0 {
+		return body
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		for _, key := range r.keys {
+			body = key.ReplaceAll(body, []byte(`${1}"`+Mask+`"`))
+		}
+		return body
+	}
+
+	for _, path := range r.fields {
+		document = redactPath(document, path)
+	}
+
+	redacted, err := json.Marshal(document)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// pathStep is one step of a field path, a key, any key or index, optionally matched at any depth below the current value.
+type pathStep struct {
+	key       string
+	any       bool
+	recursive bool
+}
+
+// parsePath parses "$.a.b", "$.a[*].b", "$.a[0]" style paths, "$..b" matching b at any depth.
+func parsePath(path string) ([]pathStep, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(path), "$")
+	if !ok {
+		return nil, fmt.Errorf("invalid redaction field %q: must start with $", path)
+	}
+
+	var steps []pathStep
+	for rest != "" {
+		var step pathStep
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			step.recursive, rest = true, rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "["):
+		default:
+			return nil, fmt.Errorf("invalid redaction field %q", path)
+		}
+
+		if strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid redaction field %q: unclosed [", path)
+			}
+			step.key, rest = strings.Trim(rest[1:end], `'"`), rest[end+1:]
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			step.key, rest = rest[:end], rest[end:]
+		}
+
+		if step.key == "" {
+			return nil, fmt.Errorf("invalid redaction field %q: empty key", path)
+		}
+		step.any = step.key == "*"
+		steps = append(steps, step)
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("invalid redaction field %q: the whole document cannot be redacted", path)
+	}
+	return steps, nil
+}
+
+// redactPath masks the values the path matches in v, returning the updated value.
+func redactPath(v any, path []pathStep) any {
+	if len(path) == 0 {
+		return Mask
+	}
+
+	step := path[0]
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if step.any || key == step.key {
+				v[key] = redactPath(child, path[1:])
+			} else if step.recursive {
+				v[key] = redactPath(child, path)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			switch {
+			case step.any || fmt.Sprint(i) == step.key:
+				v[i] = redactPath(child, path[1:])
+			case step.recursive:
+				v[i] = redactPath(child, path)
+			}
+		}
+	}
+	return v
+}
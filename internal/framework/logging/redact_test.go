@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor_Body(t *testing.T) {
+	config := DefaultRedactorConfig()
+	config.Fields = append(config.Fields, "$.user.ssn", "$.items[*].secretCode", "$.list[1]")
+	redactor, err := NewRedactor(config)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		contentType string
+		body        string
+		want        string
+	}{
+		"json field paths": {
+			contentType: "application/json",
+			body:        `{"user":{"name":"bob","ssn":"123-45-6789","password":"hunter2"},"items":[{"secretCode":1,"n":2}],"list":[1,2,3]}`,
+			want:        `{"items":[{"n":2,"secretCode":"[REDACTED]"}],"list":[1,"[REDACTED]",3],"user":{"name":"bob","password":"[REDACTED]","ssn":"[REDACTED]"}}`,
+		},
+		"nested recursive field": {
+			contentType: "application/problem+json",
+			body:        `{"a":[{"b":{"token":"t"}}]}`,
+			want:        `{"a":[{"b":{"token":"[REDACTED]"}}]}`,
+		},
+		"card numbers and emails in text": {
+			contentType: "text/plain",
+			body:        "card 4111 1111 1111 1111 from bob@example.com on order 42",
+			want:        "card [REDACTED] from [REDACTED] on order 42",
+		},
+		"long numbers failing the luhn check are kept": {
+			contentType: "text/plain",
+			body:        "tracking 1234567890123456 for card 5500-0000-0000-0004",
+			want:        "tracking 1234567890123456 for card [REDACTED]",
+		},
+		"form values": {
+			contentType: "application/x-www-form-urlencoded",
+			body:        "password=hunter2&user=bob",
+			want:        "password=%5BREDACTED%5D&user=bob",
+		},
+		"invalid json still masks patterns": {
+			contentType: "application/json",
+			body:        `{"email":"bob@example.com"`,
+			want:        `{"email":"[REDACTED]"`,
+		},
+		"truncated json masks field keys": {
+			contentType: "application/json",
+			body:        `{"user":{"ssn": "123-45-6789","password":"hun`,
+			want:        `{"user":{"ssn": "[REDACTED]","password":"[REDACTED]"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(redactor.Body(tt.contentType, []byte(tt.body))))
+		})
+	}
+}
+
+func TestRedactor_InvalidConfig(t *testing.T) {
+	_, err := NewRedactor(RedactorConfig{Fields: []string{"user.ssn"}})
+	assert.Error(t, err)
+	_, err = NewRedactor(RedactorConfig{Fields: []string{"$"}})
+	assert.Error(t, err)
+	_, err = NewRedactor(RedactorConfig{Patterns: []string{"("}})
+	assert.Error(t, err)
+}
+
+func TestHttpLoggingMiddleware_Redacts(t *testing.T) {
+	logs := Capture(t)
+
+	handler := HttpLoggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"xyz","user":"bob"}`))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/login?api_key=k1&page=2", strings.NewReader(`{"password":"hunter2"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+	handler(httptest.NewRecorder(), req)
+
+	request, ok := logs.Find("HTTP Request")
+	require.True(t, ok)
+	assert.NotContains(t, request.Attrs["headers"], "Bearer secret")
+	assert.NotContains(t, request.Attrs["headers"], "k1")
+	assert.Contains(t, request.Attrs["headers"], "page=2")
+	assert.NotContains(t, request.Attrs["body"], "hunter2")
+
+	response, ok := logs.Find("HTTP Response")
+	require.True(t, ok)
+	assert.Equal(t, []string{Mask}, response.Attrs["headers"].(http.Header)["Set-Cookie"])
+	assert.NotContains(t, response.Attrs["body"], "xyz")
+	assert.Contains(t, response.Attrs["body"], "bob")
+}
@@ -0,0 +1,298 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names rotated files, it sorts chronologically and contains no characters invalid in file names.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateConfig decides when a RotatingFile is rotated and which rotated files are kept. Zero values disable a limit.
+type RotateConfig struct {
+	// MaxSize rotates the file before a write would make it larger than this many bytes.
+	MaxSize int64
+	// MaxAge rotates the file once it has been written to for this long.
+	MaxAge time.Duration
+	// MaxBackups is how many rotated files are kept.
+	MaxBackups int
+	// Retention removes rotated files older than this.
+	Retention time.Duration
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// enabled reports whether the file is ever rotated.
+func (c RotateConfig) enabled() bool {
+	return c.MaxSize > 0 || c.MaxAge > 0
+}
+
+// RotatingFile is a log file that is renamed to a timestamped backup, such as app-2024-03-01T12-30-00.000.log, when it
+// grows too large or too old. Backups are compressed and removed in the background.
+type RotatingFile struct {
+	mu     sync.Mutex
+	path   string
+	config RotateConfig
+	file   *os.File
+	size   int64
+	opened time.Time
+	closed bool
+
+	// mill serializes compressing and removing backups
+	mill    sync.Mutex
+	milling sync.WaitGroup
+}
+
+// NewRotatingFile opens the file at path for appending, rotating it according to the config.
+func NewRotatingFile(path string, config RotateConfig) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, config: config}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file, f.size, f.opened = file, info.Size(), time.Now()
+	return nil
+}
+
+// Write appends p to the file, rotating it first when p would exceed the size limit or the file is too old. A failed
+// rotation is returned after p is appended to the file as it is.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.reopen(); err != nil {
+		return 0, err
+	}
+
+	var rotateErr error
+	tooLarge := f.config.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.config.MaxSize
+	tooOld := f.config.MaxAge > 0 && time.Since(f.opened) >= f.config.MaxAge
+	if tooLarge || tooOld {
+		if rotateErr = f.rotate(); f.file == nil {
+			return 0, rotateErr
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+// Rotate renames the file to a backup and opens a new one.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.reopen(); err != nil {
+		return err
+	}
+	return f.rotate()
+}
+
+// reopen opens the file again when a rotation could not, so a failure does not stop every later write.
+func (f *RotatingFile) reopen() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.file == nil {
+		return f.open()
+	}
+	return nil
+}
+
+// rotate renames the file to a backup and opens a new one. The file is opened again when it cannot be renamed, and is
+// left nil when it cannot be opened.
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err == nil {
+		if err = os.Rename(f.path, f.backupName(time.Now())); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	}
+	if openErr := f.open(); openErr != nil {
+		return errors.Join(err, openErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	f.milling.Add(1)
+	go func() {
+		defer f.milling.Done()
+		f.millBackups()
+	}()
+	return nil
+}
+
+// backupName inserts the time between the name and the extension of the file, followed by a counter when a backup of
+// the same millisecond exists, compressed or not, so it is never replaced.
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext) + "-" + t.Format(backupTimeFormat)
+
+	name := base + ext
+	for i := 1; exists(name) || exists(name+".gz"); i++ {
+		name = base + "-" + strconv.Itoa(i) + ext
+	}
+	return name
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+type backup struct {
+	path string
+	time time.Time
+	seq  int
+}
+
+// backups returns the rotated files of the file, the newest first.
+func (f *RotatingFile) backups() ([]backup, error) {
+	dir := filepath.Dir(f.path)
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backup
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		name, ok = strings.CutSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		if !ok {
+			continue
+		}
+		var seq int
+		if len(name) > len(backupTimeFormat) {
+			counter, ok := strings.CutPrefix(name[len(backupTimeFormat):], "-")
+			if seq, err = strconv.Atoi(counter); !ok || err != nil || seq < 1 {
+				continue
+			}
+			name = name[:len(backupTimeFormat)]
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, name, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, entry.Name()), time: t, seq: seq})
+	}
+
+	slices.SortFunc(backups, func(a, b backup) int {
+		if c := b.time.Compare(a.time); c != 0 {
+			return c
+		}
+		return b.seq - a.seq
+	})
+	return backups, nil
+}
+
+// millBackups removes the backups beyond the retention limits and compresses the others.
+func (f *RotatingFile) millBackups() {
+	f.mill.Lock()
+	defer f.mill.Unlock()
+
+	backups, err := f.backups()
+	if err != nil {
+		slog.Warn("failed to list log backups", slog.String("file", f.path), slog.String("error", err.Error()))
+		return
+	}
+
+	for i, b := range backups {
+		expired := f.config.Retention > 0 && time.Since(b.time) > f.config.Retention
+		if expired || f.config.MaxBackups > 0 && i >= f.config.MaxBackups {
+			if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				slog.Warn("failed to remove log backup", slog.String("file", b.path), slog.String("error", err.Error()))
+			}
+			continue
+		}
+
+		if f.config.Compress && !strings.HasSuffix(b.path, ".gz") {
+			if err := compress(b.path); err != nil {
+				slog.Warn("failed to compress log backup", slog.String("file", b.path), slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// compress replaces the file with a gzipped copy.
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz.tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = dst.Close()
+			_ = os.Remove(dst.Name())
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(dst.Name(), path+".gz"); err != nil {
+		return fmt.Errorf("failed to rename compressed backup: %w", err)
+	}
+	return os.Remove(path)
+}
+
+// Close closes the file and waits for backups to be compressed and removed.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	f.closed = true
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.milling.Wait()
+	return err
+}
@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	f, err := NewRotatingFile(path, RotateConfig{MaxSize: 10, MaxBackups: 2, Compress: true})
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(current))
+
+	backups, err := f.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 2, "older backups are removed")
+
+	assert.True(t, strings.HasSuffix(backups[0].path, ".log.gz"))
+	gz, err := os.Open(backups[0].path)
+	require.NoError(t, err)
+	defer gz.Close()
+	reader, err := gzip.NewReader(gz)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(content))
+}
+
+func TestRotatingFile_Retention(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	expired := filepath.Join(dir, "app-"+time.Now().Add(-48*time.Hour).Format(backupTimeFormat)+".log")
+	require.NoError(t, os.WriteFile(expired, []byte("old\n"), 0o644))
+	unrelated := filepath.Join(dir, "app-notes.log")
+	require.NoError(t, os.WriteFile(unrelated, []byte("keep\n"), 0o644))
+
+	f, err := NewRotatingFile(path, RotateConfig{MaxAge: time.Hour, Retention: 24 * time.Hour})
+	require.NoError(t, err)
+	_, err = f.Write([]byte("line\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Rotate())
+	require.NoError(t, f.Close())
+
+	backups, err := f.backups()
+	require.NoError(t, err)
+	assert.Len(t, backups, 1)
+	assert.NoFileExists(t, expired)
+	assert.FileExists(t, unrelated)
+}
+
+func TestRotatingFile_SameMillisecond(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	f, err := NewRotatingFile(path, RotateConfig{MaxSize: 10})
+	require.NoError(t, err)
+	now := time.Now()
+	first := f.backupName(now)
+	require.NoError(t, os.WriteFile(first, nil, 0o644))
+	second := f.backupName(now)
+	require.NoError(t, os.WriteFile(second+".gz", nil, 0o644))
+	third := f.backupName(now)
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, strings.TrimSuffix(first, ".log")+"-1.log", second, "an existing backup is never replaced")
+	assert.Equal(t, strings.TrimSuffix(first, ".log")+"-2.log", third, "nor is a compressed one")
+
+	require.NoError(t, os.WriteFile(third, nil, 0o644))
+	backups, err := f.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+	assert.Equal(t, []string{third, second + ".gz", first}, []string{backups[0].path, backups[1].path, backups[2].path})
+}
+
+func TestRotatingFile_RotateFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	path := filepath.Join(dir, "app.log")
+
+	f, err := NewRotatingFile(path, RotateConfig{MaxSize: 10})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+	_, err = f.Write([]byte("first\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, os.RemoveAll(dir))
+	_, err = f.Write([]byte("second\n"))
+	assert.Error(t, err, "the file cannot be opened again")
+
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	_, err = f.Write([]byte("third\n"))
+	require.NoError(t, err, "writes resume once the file can be opened")
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(current))
+}
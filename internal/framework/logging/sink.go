@@ -0,0 +1,174 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/taylorono/go-webservice/internal/framework/config"
+	"github.com/taylorono/go-webservice/internal/framework/metrics"
+)
+
+const _droppedRecordsCounter = "log_dropped_records_total"
+
+// dropMetrics is the registry counting records dropped by async outputs, set by RegisterMetrics.
+var dropMetrics atomic.Pointer[metrics.Registry]
+
+// RegisterMetrics counts the records dropped by async outputs in the registry, labelled by output.
+func RegisterMetrics(registry metrics.Registry) {
+	registry.RegisterCounter(_droppedRecordsCounter, "Log records dropped because the async buffer was full", "output")
+	dropMetrics.Store(&registry)
+}
+
+func droppedRecord(output string) func() {
+	return func() {
+		if registry := dropMetrics.Load(); registry != nil {
+			(*registry).IncCounter(_droppedRecordsCounter, 1, output)
+		}
+	}
+}
+
+// writerOptions decide how outputs are written, they apply to every file and async output.
+type writerOptions struct {
+	rotate      RotateConfig
+	async       bool
+	asyncBuffer int
+}
+
+// extraSink is an additional output receiving the records from a level.
+type extraSink struct {
+	output string
+	level  slog.Level
+	format string
+}
+
+func writerConfig(registry *config.Configuration) (writerOptions, error) {
+	options := writerOptions{
+		rotate: RotateConfig{
+			MaxSize:    int64(registry.GetSizeInBytes(keyRotateMaxSize)),
+			MaxAge:     registry.GetDuration(keyRotateMaxAge),
+			MaxBackups: registry.GetInt(keyRotateMaxBackups),
+			Retention:  registry.GetDuration(keyRotateRetention),
+			Compress:   registry.GetBool(keyRotateCompress),
+		},
+		async:       registry.GetBool(keyAsync),
+		asyncBuffer: defaultAsyncBuffer,
+	}
+	if registry.IsSet(keyAsyncBuffer) {
+		options.asyncBuffer = registry.GetInt(keyAsyncBuffer)
+	}
+
+	switch {
+	case options.rotate.MaxAge < 0:
+		return options, fmt.Errorf("invalid %s: must not be negative", keyRotateMaxAge)
+	case options.rotate.MaxBackups < 0:
+		return options, fmt.Errorf("invalid %s: must not be negative", keyRotateMaxBackups)
+	case options.rotate.Retention < 0:
+		return options, fmt.Errorf("invalid %s: must not be negative", keyRotateRetention)
+	case options.asyncBuffer <= 0:
+		return options, fmt.Errorf("invalid %s: must be positive", keyAsyncBuffer)
+	}
+	return options, nil
+}
+
+// extraSinks reads log-sinks either as a list of {output, level, format} in a config file or as a comma separated list
+// of output=level pairs. Sinks use the log format unless they set their own.
+func extraSinks(registry *config.Configuration, format string) ([]extraSink, error) {
+	type sinkConfig struct {
+		Output string
+		Level  string
+		Format string
+	}
+
+	var configs []sinkConfig
+	if value, ok := registry.Get(keySinks).(string); ok {
+		for _, pair := range strings.Split(value, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			output, lvl, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid %s entry %q: expected output=level", keySinks, pair)
+			}
+			configs = append(configs, sinkConfig{Output: strings.TrimSpace(output), Level: strings.TrimSpace(lvl)})
+		}
+	} else if err := registry.UnmarshalKey(keySinks, &configs); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", keySinks, err)
+	}
+
+	sinks := make([]extraSink, 0, len(configs))
+	for _, c := range configs {
+		s := extraSink{output: c.Output, format: strings.ToLower(c.Format)}
+		if s.output == "" {
+			return nil, fmt.Errorf("invalid %s: output is required", keySinks)
+		}
+		if err := s.level.UnmarshalText([]byte(c.Level)); err != nil {
+			return nil, fmt.Errorf("invalid %s level for %q: %w", keySinks, s.output, err)
+		}
+		if s.format == "" {
+			s.format = format
+		}
+		if s.format != "text" && s.format != "json" {
+			return nil, fmt.Errorf("invalid %s format for %q: must be text or json", keySinks, s.output)
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// open opens the output configured by key, stdout, stderr or a file that is appended to and rotated when configured,
+// written in the background when async. The closer is nil when there is nothing to close.
+func open(key, output string, options writerOptions) (io.Writer, io.Closer, error) {
+	var (
+		w      io.Writer
+		closer closeAll
+	)
+	switch output {
+	case "", "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	default:
+		var (
+			f   io.WriteCloser
+			err error
+		)
+		if options.rotate.enabled() {
+			f, err = NewRotatingFile(output, options.rotate)
+		} else {
+			f, err = os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		w, closer = f, closeAll{f}
+	}
+
+	if options.async {
+		async := NewAsyncWriter(w, options.asyncBuffer, droppedRecord(output))
+		// pending records are written before the file is closed
+		w, closer = async, append(closeAll{async}, closer...)
+	}
+
+	if len(closer) == 0 {
+		return w, nil, nil
+	}
+	return w, closer, nil
+}
+
+// closeAll closes each closer in order.
+type closeAll []io.Closer
+
+func (c closeAll) Close() error {
+	var errs []error
+	for _, closer := range c {
+		if closer != nil {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
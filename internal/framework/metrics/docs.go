@@ -8,10 +8,10 @@ import (
 )
 
 const metricTpl = `# Service metrics
-| Metric | Description | Type | Labels |
-|--------|-------------|------|--------|
+| Metric | Description | Type | Aggregation | Labels |
+|--------|-------------|------|-------------|--------|
 {{- range .Definitions }}
-| {{.Name}} | {{.Definition.Description}} | {{.Definition.Kind}} | {{.Definition.Labels | splitter }} |
+| {{.Name}} | {{.Definition.Description}} | {{.Definition.Kind}} | {{.Definition.Aggregation}} | {{.Definition.Labels | splitter }} |
 {{- end }}
 `
 
@@ -31,6 +31,10 @@ func MetricDocs(metricsReporter Reporter) http.HandlerFunc {
 		values := make([]metricDefinition, len(metrics))
 		ndx := 0
 		for k, v := range metrics {
+			// list metrics by the name they are exposed as, which a reporter may prefix
+			if v.Name != "" {
+				k = v.Name
+			}
 			values[ndx] = metricDefinition{Name: k, Definition: v}
 			ndx = ndx + 1
 		}
@@ -25,8 +25,12 @@ type Reporter interface {
 }
 
 type MetricDefinition struct {
+	// Name is the name the metric is exposed as, prefixed by the namespace and subsystem of a PrometheusReporter.
+	Name        string
 	Kind        string
 	Description string
+	// Aggregation describes how a summary or histogram is materialized by the reporter.
+	Aggregation string
 	labelCount  int
 	Labels      []string
 }
@@ -1,6 +1,10 @@
 package metrics
 
 import (
+	"bufio"
+	"context"
+	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,9 +14,23 @@ import (
 const (
 	_incomingReqHist    = "app_request_latency_histogram"
 	_incomingReqSummary = "app_request_latency"
+	_incomingReqErrors  = "app_request_errors_total"
+
+	// UnmatchedPath is the path label used for requests that matched no route, keeping label cardinality bounded.
+	UnmatchedPath = "<unmatched>"
+
+	// OtherMethod is the method label used for requests with a method other than the standard ones, which clients
+	// choose freely.
+	OtherMethod = "OTHER"
 )
 
+// Registry registers metrics and records observations of them. Observations a reporter cannot record, of metrics not
+// registered with the kind observed or with a different number of label values, are handled according to its Policy.
 type Registry interface {
+	RegisterCounter(name string, description string, labels ...string)
+	IncCounter(name string, value float64, labels ...string)
+	RegisterGauge(name string, description string, labels ...string)
+	SetGauge(name string, value float64, labels ...string)
 	RegisterHistogram(name string, description string, buckets []float64, labels ...string)
 	RegisterSummary(name string, description string, quantiles map[float64]float64, labels ...string)
 	ObserveHistogram(name string, value float64, labels ...string)
@@ -23,15 +41,20 @@ type Registry interface {
 func HttpMiddleware(registry Registry) func(next http.HandlerFunc) http.HandlerFunc {
 	registry.RegisterHistogram(_incomingReqHist, "Service response time", defaultBuckets, "method", "path")
 	registry.RegisterSummary(_incomingReqSummary, "Service response time with more labels", map[float64]float64{}, "method", "path", "status_code")
+	registry.RegisterCounter(_incomingReqErrors, "Service errors by kind", "method", "path", "kind")
 
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			recorder := newResponseRecorder(w)
 
-			path := strings.Split(r.Pattern, " ")
+			var errorKind string
+			r = r.WithContext(context.WithValue(r.Context(), errorKindKey{}, &errorKind))
+
 			defer func(start time.Time) {
-				registry.ObserveHistogram(_incomingReqHist, ToMilliseconds(time.Since(start)), r.Method, path[len(path)-1])
-				registry.ObserveSummary(_incomingReqSummary, ToMilliseconds(time.Since(start)), r.Method, path[len(path)-1], strconv.Itoa(recorder.statusCode))
+				observe(registry, r, recorder.statusCode, time.Since(start))
+				if errorKind != "" {
+					registry.IncCounter(_incomingReqErrors, 1, method(r), RoutePath(r), errorKind)
+				}
 			}(time.Now())
 
 			next.ServeHTTP(recorder, r)
@@ -39,6 +62,60 @@ func HttpMiddleware(registry Registry) func(next http.HandlerFunc) http.HandlerF
 	}
 }
 
+type errorKindKey struct{}
+
+// SetErrorKind labels the error metric of a request instrumented by HttpMiddleware with the kind of error it failed with.
+func SetErrorKind(ctx context.Context, kind string) {
+	if errorKind, ok := ctx.Value(errorKindKey{}).(*string); ok {
+		*errorKind = kind
+	}
+}
+
+// UnmatchedHttpMiddleware creates http middleware intended to wrap an entire http.ServeMux. It records requests that
+// matched no route, such as 404 and 405 responses, under the UnmatchedPath label using the metrics registered by
+// HttpMiddleware. Matched requests are left to HttpMiddleware. The mux sets the matched pattern on the request it is given,
+// so middleware between this and the mux must not replace the request.
+func UnmatchedHttpMiddleware(registry Registry) func(next http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			recorder := newResponseRecorder(w)
+			start := time.Now()
+
+			next.ServeHTTP(recorder, r)
+
+			if r.Pattern == "" {
+				observe(registry, r, recorder.statusCode, time.Since(start))
+			}
+		}
+	}
+}
+
+func observe(registry Registry, r *http.Request, statusCode int, duration time.Duration) {
+	path := RoutePath(r)
+	registry.ObserveHistogram(_incomingReqHist, ToMilliseconds(duration), method(r), path)
+	registry.ObserveSummary(_incomingReqSummary, ToMilliseconds(duration), method(r), path, strconv.Itoa(statusCode))
+}
+
+// method returns the method of the request, or OtherMethod when it is not one of the standard methods.
+func method(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return r.Method
+	}
+	return OtherMethod
+}
+
+// RoutePath returns the path of the pattern the request matched, or UnmatchedPath.
+func RoutePath(r *http.Request) string {
+	if r.Pattern == "" {
+		return UnmatchedPath
+	}
+
+	pattern := strings.Split(r.Pattern, " ")
+	return pattern[len(pattern)-1]
+}
+
 type responseRecorder struct {
 	http.ResponseWriter
 	statusCode int
@@ -52,3 +129,26 @@ func (lrw *responseRecorder) WriteHeader(code int) {
 	lrw.statusCode = code
 	lrw.ResponseWriter.WriteHeader(code)
 }
+
+// Flush keeps streaming responses working for handlers asserting http.Flusher.
+func (lrw *responseRecorder) Flush() {
+	_ = http.NewResponseController(lrw.ResponseWriter).Flush()
+}
+
+// Hijack keeps protocol upgrades working for handlers asserting http.Hijacker.
+func (lrw *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(lrw.ResponseWriter).Hijack()
+}
+
+// ReadFrom lets the underlying writer copy directly, for example with sendfile.
+func (lrw *responseRecorder) ReadFrom(src io.Reader) (int64, error) {
+	if readerFrom, ok := lrw.ResponseWriter.(io.ReaderFrom); ok {
+		return readerFrom.ReadFrom(src)
+	}
+	return io.Copy(lrw.ResponseWriter, src)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (lrw *responseRecorder) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmatchedHttpMiddleware(t *testing.T) {
+	reporter := NewPrometheusReporter()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", HttpMiddleware(reporter)(func(w http.ResponseWriter, r *http.Request) {}))
+	handler := UnmatchedHttpMiddleware(reporter)(mux.ServeHTTP)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/items/1", nil),
+		httptest.NewRequest(http.MethodGet, "/missing", nil),
+		httptest.NewRequest(http.MethodPost, "/items/1", nil),
+		httptest.NewRequest("BREW", "/missing", nil),
+	} {
+		handler(httptest.NewRecorder(), req)
+	}
+
+	body := scrape(t, reporter)
+	assert.Contains(t, body, `app_request_latency_count{method="GET",path="/items/{id}",status_code="200"} 1`,
+		"matched requests are only recorded by HttpMiddleware")
+	assert.Contains(t, body, `app_request_latency_histogram_count{method="GET",path="/items/{id}"} 1`)
+	assert.Contains(t, body, `app_request_latency_count{method="GET",path="<unmatched>",status_code="404"} 1`)
+	assert.Contains(t, body, `app_request_latency_count{method="POST",path="<unmatched>",status_code="405"} 1`)
+	assert.NotContains(t, body, `path="/missing"`, "unmatched paths do not become labels")
+	assert.Contains(t, body, `app_request_latency_count{method="OTHER",path="<unmatched>",status_code="404"} 1`)
+	assert.NotContains(t, body, `method="BREW"`, "non-standard methods do not become labels")
+}
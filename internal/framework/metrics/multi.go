@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// MultiReporter registers and records every metric with all of its reporters, for example with a PrometheusReporter
+// and an OTELReporter while migrating from one to the other.
+type MultiReporter struct {
+	reporters []Reporter
+}
+
+// NewMultiReporter creates a reporter fanning out to the reporters. It fails when they already define a metric
+// differently.
+func NewMultiReporter(reporters ...Reporter) (*MultiReporter, error) {
+	if _, err := mergeDefinitions(reporters); err != nil {
+		return nil, err
+	}
+	return &MultiReporter{reporters: reporters}, nil
+}
+
+// register checks the metric is not defined differently by any of the reporters, then registers it with all of them.
+// It panics on a conflict like the reporters do for a metric registered twice.
+func (m *MultiReporter) register(name string, kind string, labels []string, register func(Reporter)) {
+	sanitize(labels)
+	for _, reporter := range m.reporters {
+		if existing, ok := reporter.GetMetricsDefinition()[name]; ok && !compatible(existing, MetricDefinition{Kind: kind, Labels: labels}) {
+			panic(fmt.Sprintf("metrics: cannot register %s %s with labels %q: already a %s with labels %q",
+				kind, name, labels, existing.Kind, existing.Labels))
+		}
+	}
+	for _, reporter := range m.reporters {
+		register(reporter)
+	}
+}
+
+func (m *MultiReporter) RegisterCounter(name string, description string, labels ...string) {
+	m.register(name, metricTypeCounter, labels, func(r Reporter) { r.RegisterCounter(name, description, labels...) })
+}
+
+func (m *MultiReporter) RegisterGauge(name string, description string, labels ...string) {
+	m.register(name, metricTypeGauge, labels, func(r Reporter) { r.RegisterGauge(name, description, labels...) })
+}
+
+func (m *MultiReporter) RegisterSummary(name string, description string, quantiles map[float64]float64, labels ...string) {
+	m.register(name, metricTypeSummary, labels, func(r Reporter) { r.RegisterSummary(name, description, quantiles, labels...) })
+}
+
+func (m *MultiReporter) RegisterHistogram(name string, description string, buckets []float64, labels ...string) {
+	m.register(name, metricTypeHistogram, labels, func(r Reporter) { r.RegisterHistogram(name, description, buckets, labels...) })
+}
+
+func (m *MultiReporter) IncCounter(name string, value float64, labels ...string) {
+	for _, reporter := range m.reporters {
+		reporter.IncCounter(name, value, labels...)
+	}
+}
+
+func (m *MultiReporter) SetGauge(name string, value float64, labels ...string) {
+	for _, reporter := range m.reporters {
+		reporter.SetGauge(name, value, labels...)
+	}
+}
+
+func (m *MultiReporter) ObserveSummary(name string, value float64, labels ...string) {
+	for _, reporter := range m.reporters {
+		reporter.ObserveSummary(name, value, labels...)
+	}
+}
+
+func (m *MultiReporter) ObserveHistogram(name string, value float64, labels ...string) {
+	for _, reporter := range m.reporters {
+		reporter.ObserveHistogram(name, value, labels...)
+	}
+}
+
+// Routes serves the merged metric docs, and on /metrics the metrics of every reporter exposing a Gatherer. A family
+// gathered from several reporters is served from the first one.
+func (m *MultiReporter) Routes(mux *http.ServeMux) {
+	var gatherers firstGatherers
+	for _, reporter := range m.reporters {
+		if g, ok := reporter.(interface{ Gatherer() prometheus.Gatherer }); ok && g.Gatherer() != nil {
+			gatherers = append(gatherers, g.Gatherer())
+		}
+	}
+
+	if len(gatherers) > 0 {
+		mux.Handle("/metrics", promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+			ErrorLog:      slogPrinter{},
+			ErrorHandling: promhttp.ContinueOnError,
+		}))
+	}
+	mux.HandleFunc("/metrics/docs", MetricDocs(m))
+}
+
+// GetMetricsDefinition returns the definitions of all the reporters, with the name and aggregation of each reporter when
+// they differ. The first reporter wins a conflict between metrics registered directly with the reporters.
+func (m *MultiReporter) GetMetricsDefinition() map[string]MetricDefinition {
+	definitions, _ := mergeDefinitions(m.reporters)
+	return definitions
+}
+
+// mergeDefinitions merges the definitions of the reporters, reporting the metrics defined with different kinds or labels.
+func mergeDefinitions(reporters []Reporter) (map[string]MetricDefinition, error) {
+	definitions := make(map[string]MetricDefinition)
+	var conflicts []string
+
+	for _, reporter := range reporters {
+		for name, definition := range reporter.GetMetricsDefinition() {
+			existing, ok := definitions[name]
+			switch {
+			case !ok:
+				definitions[name] = definition
+			case !compatible(existing, definition):
+				conflicts = append(conflicts, fmt.Sprintf("%s is a %s with labels %q and a %s with labels %q",
+					name, existing.Kind, existing.Labels, definition.Kind, definition.Labels))
+			default:
+				// reporters may expose the metric under different names and materialize it differently
+				existing.Name = joinDistinct(existing.Name, definition.Name, ", ")
+				existing.Aggregation = joinDistinct(existing.Aggregation, definition.Aggregation, "; ")
+				definitions[name] = existing
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		slices.Sort(conflicts)
+		return definitions, fmt.Errorf("conflicting metric definitions: %s", strings.Join(conflicts, ", "))
+	}
+	return definitions, nil
+}
+
+// compatible reports whether the definitions have the same kind and labels.
+func compatible(a, b MetricDefinition) bool {
+	return a.Kind == b.Kind && slices.Equal(a.Labels, b.Labels)
+}
+
+// joinDistinct appends value to the values joined by sep unless it is empty or already one of them.
+func joinDistinct(values, value, sep string) string {
+	switch {
+	case value == "" || slices.Contains(strings.Split(values, sep), value):
+		return values
+	case values == "":
+		return value
+	}
+	return values + sep + value
+}
+
+// firstGatherers gathers the families of all the gatherers, keeping the first family of every name since reporters
+// record the same metrics with different types.
+type firstGatherers []prometheus.Gatherer
+
+func (g firstGatherers) Gather() ([]*dto.MetricFamily, error) {
+	var (
+		families []*dto.MetricFamily
+		seen     = make(map[string]bool)
+		errs     prometheus.MultiError
+	)
+	for _, gatherer := range g {
+		gathered, err := gatherer.Gather()
+		errs.Append(err)
+		for _, family := range gathered {
+			if !seen[family.GetName()] {
+				seen[family.GetName()] = true
+				families = append(families, family)
+			}
+		}
+	}
+	slices.SortFunc(families, func(a, b *dto.MetricFamily) int { return strings.Compare(a.GetName(), b.GetName()) })
+	return families, errs.MaybeUnwrap()
+}
+
+// slogPrinter logs the errors of the /metrics handler.
+type slogPrinter struct{}
+
+func (slogPrinter) Println(v ...any) {
+	slog.Error("failed to gather metrics", slog.String("error", fmt.Sprint(v...)))
+}
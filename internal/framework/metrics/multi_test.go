@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiReporter(t *testing.T) {
+	provider, err := NewOTELProvider(context.Background(), OTELConfig{Prometheus: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	prom := NewPrometheusReporter()
+	otel := NewOTELReporter(WithOTELProvider(provider))
+	reporter, err := NewMultiReporter(prom, otel)
+	require.NoError(t, err)
+
+	reporter.RegisterCounter("multi_requests_total", "test counter", "kind")
+	reporter.RegisterSummary("multi_duration", "test summary", map[float64]float64{0.5: 0.05})
+	reporter.IncCounter("multi_requests_total", 2, "a")
+	reporter.ObserveSummary("multi_duration", 3)
+
+	assert.Contains(t, scrape(t, prom), `multi_requests_total{kind="a"} 2`)
+	assert.Contains(t, family(t, provider, "multi_requests_total").GetMetric()[0].GetCounter().String(), "value:2")
+
+	definition := reporter.GetMetricsDefinition()["multi_duration"]
+	assert.Equal(t, metricTypeSummary, definition.Kind)
+	assert.Equal(t, "summary, quantiles 0.5; explicit bucket histogram, 15 buckets", definition.Aggregation)
+
+	mux := http.NewServeMux()
+	assert.NotPanics(t, func() { reporter.Routes(mux) }, "routes of the reporters are combined")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `multi_requests_total{kind="a"} 2`)
+	assert.Contains(t, rec.Body.String(), "target_info", "metrics of the otel exporter are served too")
+	assert.Contains(t, rec.Body.String(), `multi_duration{quantile="0.5"}`, "the first reporter wins a family of both")
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics/docs", nil))
+	assert.Contains(t, rec.Body.String(), "| multi_requests_total | test counter | counter |")
+}
+
+func TestMultiReporter_Conflicts(t *testing.T) {
+	first, second := NewPrometheusReporter(), NewPrometheusReporter()
+	first.RegisterCounter("conflict_total", "test counter", "kind")
+	second.RegisterGauge("conflict_total", "test gauge")
+
+	_, err := NewMultiReporter(first, second)
+	assert.ErrorContains(t, err, "conflict_total is a counter")
+
+	second = NewPrometheusReporter()
+	reporter, err := NewMultiReporter(first, second)
+	require.NoError(t, err)
+	assert.Panics(t, func() { reporter.RegisterCounter("conflict_total", "test counter", "other") })
+	assert.NotContains(t, second.GetMetricsDefinition(), "conflict_total", "nothing is registered on a conflict")
+}
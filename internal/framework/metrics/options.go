@@ -0,0 +1,89 @@
+package metrics
+
+// Option configures a reporter.
+type Option func(*options)
+
+type options struct {
+	policy             Policy
+	namespace          string
+	subsystem          string
+	constLabels        map[string]string
+	goCollector        bool
+	processCollector   bool
+	buildInfoCollector bool
+	otelProvider       *OTELProvider
+}
+
+func newOptions(opts []Option) options {
+	o := options{policy: Lenient}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithPolicy sets what the reporter does with observations it cannot record, Lenient by default.
+func WithPolicy(policy Policy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithNamespace prefixes the names of the metrics of a PrometheusReporter with the namespace.
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithSubsystem prefixes the names of the metrics of a PrometheusReporter with the subsystem, after the namespace.
+func WithSubsystem(subsystem string) Option {
+	return func(o *options) {
+		o.subsystem = subsystem
+	}
+}
+
+// WithConstLabels adds the labels, such as service, version and pod, to every metric of a PrometheusReporter. Labels
+// with an empty value are left out.
+func WithConstLabels(labels map[string]string) Option {
+	return func(o *options) {
+		for name, value := range labels {
+			if value == "" {
+				continue
+			}
+			if o.constLabels == nil {
+				o.constLabels = map[string]string{}
+			}
+			o.constLabels[name] = value
+		}
+	}
+}
+
+// WithGoCollector exports the Go runtime metrics, such as go_goroutines, from a PrometheusReporter.
+func WithGoCollector() Option {
+	return func(o *options) {
+		o.goCollector = true
+	}
+}
+
+// WithProcessCollector exports the process metrics, such as process_cpu_seconds_total, from a PrometheusReporter.
+func WithProcessCollector() Option {
+	return func(o *options) {
+		o.processCollector = true
+	}
+}
+
+// WithBuildInfoCollector exports go_build_info with the module path and version from a PrometheusReporter.
+func WithBuildInfoCollector() Option {
+	return func(o *options) {
+		o.buildInfoCollector = true
+	}
+}
+
+// WithOTELProvider makes an OTELReporter record its metrics with the provider instead of the global MeterProvider and
+// serve the metrics of its Prometheus exporter, if any, on /metrics.
+func WithOTELProvider(provider *OTELProvider) Option {
+	return func(o *options) {
+		o.otelProvider = provider
+	}
+}
@@ -5,43 +5,93 @@ import (
 	"net/http"
 	"sync"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
+// _meterName is the instrumentation scope of the metrics of an OTELReporter.
+const _meterName = "otel-reporter"
+
+// OTELReporter records metrics with an OpenTelemetry MeterProvider, see NewOTELProvider to create one.
 type OTELReporter struct {
 	sync.RWMutex
 	meter                metric.Meter
+	gatherer             prometheus.Gatherer
+	views                *views
 	metricRegistry       map[string]MetricDefinition
 	counterDefinitions   map[string]metric.Float64Counter
 	gaugeDefinitions     map[string]metric.Float64Gauge
 	summaryDefinitions   map[string]metric.Float64Histogram
 	histogramDefinitions map[string]metric.Float64Histogram
+	dropper              *dropper
 }
 
-func NewOTELReporter() *OTELReporter {
-	return &OTELReporter{
-		meter:                otel.GetMeterProvider().Meter("otel-reporter"),
+// NewOTELReporter creates a reporter recording with the global MeterProvider, which is a no-op unless an SDK provider
+// has been installed, or with the provider of WithOTELProvider.
+func NewOTELReporter(opts ...Option) *OTELReporter {
+	o := newOptions(opts)
+
+	var provider metric.MeterProvider = otel.GetMeterProvider()
+	if o.otelProvider != nil {
+		provider = o.otelProvider
+	}
+	meter := provider.Meter(_meterName)
+	dropped, err := meter.Float64Counter(_droppedObservations, metric.WithDescription(_droppedDescription))
+	if err != nil {
+		panic(err)
+	}
+
+	r := &OTELReporter{
+		meter:                meter,
 		metricRegistry:       make(map[string]MetricDefinition),
 		counterDefinitions:   make(map[string]metric.Float64Counter),
 		gaugeDefinitions:     make(map[string]metric.Float64Gauge),
 		summaryDefinitions:   make(map[string]metric.Float64Histogram),
 		histogramDefinitions: make(map[string]metric.Float64Histogram),
+		dropper: &dropper{
+			policy: o.policy,
+			count: func(name, reason string) {
+				dropped.Add(context.Background(), 1, metric.WithAttributeSet(toAttributeSet(_droppedLabels, []string{name, reason})))
+			},
+		},
+	}
+	if o.otelProvider != nil {
+		r.gatherer = o.otelProvider.gatherer
+		r.views = o.otelProvider.views
 	}
+	r.registerMetrics(_droppedObservations, _droppedDescription, metricTypeCounter, _droppedLabels)
+	return r
 }
 
 func (r *OTELReporter) registerMetrics(name string, description string, kind string, labels []string) {
+	r.registerAggregation(name, description, kind, "", labels)
+}
+
+func (r *OTELReporter) registerAggregation(name string, description string, kind string, aggregation string, labels []string) {
 	r.Lock()
 	r.metricRegistry[name] = MetricDefinition{
+		Name:        name,
 		Kind:        kind,
 		Description: description,
+		Aggregation: aggregation,
 		Labels:      labels,
 		labelCount:  len(labels),
 	}
 	r.Unlock()
 }
 
+// aggregation describes how a summary or a histogram with the buckets is recorded. Without an SDK provider the views
+// are unknown and histograms keep their buckets.
+func (r *OTELReporter) aggregation(name string, summary bool, buckets []float64) string {
+	if r.views == nil {
+		return explicitHistogram(buckets)
+	}
+	return r.views.describe(name, summary, buckets)
+}
+
 func (r *OTELReporter) RegisterCounter(name string, description string, labels ...string) {
 	sanitize(labels)
 	r.registerMetrics(name, description, metricTypeCounter, labels)
@@ -68,9 +118,15 @@ func (r *OTELReporter) RegisterGauge(name string, description string, labels ...
 	r.Unlock()
 }
 
+// RegisterSummary records the summary with a base-2 exponential histogram when the reporter uses an OTELProvider without
+// the Prometheus pull exporter, as OpenTelemetry has no summary instrument, so the quantiles are computed by the backend.
+// Otherwise it is an explicit bucket histogram with the default buckets.
 func (r *OTELReporter) RegisterSummary(name string, description string, _ map[float64]float64, labels ...string) {
 	sanitize(labels)
-	r.registerMetrics(name, description, metricTypeSummary, labels)
+	if r.views != nil {
+		r.views.summary(name)
+	}
+	r.registerAggregation(name, description, metricTypeSummary, r.aggregation(name, true, defaultBuckets), labels)
 
 	r.Lock()
 	histogram, err := r.meter.Float64Histogram(
@@ -87,11 +143,10 @@ func (r *OTELReporter) RegisterSummary(name string, description string, _ map[fl
 
 func (r *OTELReporter) RegisterHistogram(name string, description string, buckets []float64, labels ...string) {
 	sanitize(labels)
-	r.registerMetrics(name, description, metricTypeHistogram, labels)
-
 	if len(buckets) == 0 {
 		buckets = defaultBuckets
 	}
+	r.registerAggregation(name, description, metricTypeHistogram, r.aggregation(name, false, buckets), labels)
 
 	r.Lock()
 	histogram, err := r.meter.Float64Histogram(
@@ -108,64 +163,71 @@ func (r *OTELReporter) RegisterHistogram(name string, description string, bucket
 
 func (r *OTELReporter) IncCounter(name string, value float64, labels ...string) {
 	r.RLock()
-	if meter, ok := r.counterDefinitions[name]; ok {
-		labelCount := r.metricRegistry[name].labelCount
-		if labelCount == len(labels) {
-			attributes := toAttributeSet(r.metricRegistry[name].Labels, labels)
-			meter.Add(context.Background(), value, metric.WithAttributeSet(attributes))
-		} else {
-			// Error
-		}
+	defer r.RUnlock()
+
+	if meter, ok := r.counterDefinitions[name]; r.accepts(name, ok, labels) {
+		attributes := toAttributeSet(r.metricRegistry[name].Labels, labels)
+		meter.Add(context.Background(), value, metric.WithAttributeSet(attributes))
 	}
-	r.RUnlock()
 }
 
 func (r *OTELReporter) SetGauge(name string, value float64, labels ...string) {
 	r.RLock()
-	if meter, ok := r.gaugeDefinitions[name]; ok {
-		labelCount := r.metricRegistry[name].labelCount
-		if labelCount == len(labels) {
-			attributes := toAttributeSet(r.metricRegistry[name].Labels, labels)
-			meter.Record(context.Background(), value, metric.WithAttributeSet(attributes))
-		} else {
-			// Error
-		}
+	defer r.RUnlock()
+
+	if meter, ok := r.gaugeDefinitions[name]; r.accepts(name, ok, labels) {
+		attributes := toAttributeSet(r.metricRegistry[name].Labels, labels)
+		meter.Record(context.Background(), value, metric.WithAttributeSet(attributes))
 	}
-	r.RUnlock()
 }
 
 func (r *OTELReporter) ObserveSummary(name string, value float64, labels ...string) {
 	r.RLock()
-	if meter, ok := r.summaryDefinitions[name]; ok {
-		labelCount := r.metricRegistry[name].labelCount
-		if labelCount == len(labels) {
-			attributes := toAttributeSet(r.metricRegistry[name].Labels, labels)
-			meter.Record(context.Background(), value, metric.WithAttributeSet(attributes))
-		} else {
-			// Error
-		}
+	defer r.RUnlock()
+
+	if meter, ok := r.summaryDefinitions[name]; r.accepts(name, ok, labels) {
+		attributes := toAttributeSet(r.metricRegistry[name].Labels, labels)
+		meter.Record(context.Background(), value, metric.WithAttributeSet(attributes))
 	}
-	r.RUnlock()
 }
 
 func (r *OTELReporter) ObserveHistogram(name string, value float64, labels ...string) {
 	r.RLock()
-	if meter, ok := r.histogramDefinitions[name]; ok {
-		labelCount := r.metricRegistry[name].labelCount
-		if labelCount == len(labels) {
-			attributes := toAttributeSet(r.metricRegistry[name].Labels, labels)
-			meter.Record(context.Background(), value, metric.WithAttributeSet(attributes))
-		} else {
-			// Error
-		}
+	defer r.RUnlock()
+
+	if meter, ok := r.histogramDefinitions[name]; r.accepts(name, ok, labels) {
+		attributes := toAttributeSet(r.metricRegistry[name].Labels, labels)
+		meter.Record(context.Background(), value, metric.WithAttributeSet(attributes))
 	}
-	r.RUnlock()
 }
 
+// accepts reports whether the metric is registered with as many labels as there are values, dropping the observation if
+// not. It must be called with the read lock held.
+func (r *OTELReporter) accepts(name string, registered bool, labels []string) bool {
+	if !registered {
+		r.dropper.drop(name, ReasonUnknownMetric, labels)
+		return false
+	}
+	if r.metricRegistry[name].labelCount != len(labels) {
+		r.dropper.drop(name, ReasonLabelMismatch, labels)
+		return false
+	}
+	return true
+}
+
+// Routes serves the metric docs, and the metrics on /metrics when the provider has a Prometheus exporter.
 func (r *OTELReporter) Routes(mux *http.ServeMux) {
+	if r.gatherer != nil {
+		mux.Handle("/metrics", promhttp.HandlerFor(r.gatherer, promhttp.HandlerOpts{}))
+	}
 	mux.HandleFunc("/metrics/docs", MetricDocs(r))
 }
 
+// Gatherer returns the metrics of the Prometheus exporter of the provider, or nil without one.
+func (r *OTELReporter) Gatherer() prometheus.Gatherer {
+	return r.gatherer
+}
+
 // GetMetricsDefinition returns the definition of all the metrics that have been registered using this package
 func (r *OTELReporter) GetMetricsDefinition() map[string]MetricDefinition {
 	// Creating a copy to avoid exposing the internal map to external manipulation
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// defaultExportInterval is how often metrics are pushed unless configured otherwise.
+const defaultExportInterval = time.Minute
+
+// OTLP protocols accepted by OTELConfig.Protocol.
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// OTELConfig configures the MeterProvider created by NewOTELProvider.
+type OTELConfig struct {
+	// ServiceName, ServiceVersion and ServiceInstance describe the service in the resource of every metric. The instance
+	// defaults to the host name.
+	ServiceName     string
+	ServiceVersion  string
+	ServiceInstance string
+
+	// Protocol pushes metrics over OTLP with "grpc" or "http" to Endpoint every Interval, a minute by default. The
+	// endpoint is a host:port or a URL, which for http includes the path such as http://collector:4318/v1/metrics. The
+	// OTEL_EXPORTER_OTLP_* environment variables apply when it is empty. No metrics are pushed without a protocol.
+	Protocol string
+	Endpoint string
+	Insecure bool
+	Interval time.Duration
+
+	// Prometheus adds a pull exporter whose metrics an OTELReporter created with the provider serves on /metrics.
+	// Summaries then keep explicit buckets, which its text format exposes.
+	Prometheus bool
+
+	// Views customize the aggregation, name and attributes of the metrics of an OTELReporter, the first matching view
+	// applies.
+	Views []OTELView
+}
+
+// OTELProvider is an SDK MeterProvider with the exporters of an OTELConfig.
+type OTELProvider struct {
+	*sdkmetric.MeterProvider
+	gatherer prometheus.Gatherer
+	views    *views
+}
+
+// NewOTELProvider creates a MeterProvider exporting metrics as configured. It must be shut down to push the last
+// metrics.
+func NewOTELProvider(ctx context.Context, config OTELConfig) (*OTELProvider, error) {
+	for _, view := range config.Views {
+		if err := view.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := otelResource(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &OTELProvider{views: &views{config: config.Views, explicitSummaries: config.Prometheus}}
+	opts := []sdkmetric.Option{sdkmetric.WithResource(res), sdkmetric.WithView(provider.views.stream)}
+
+	if config.Protocol != "" {
+		exporter, err := otlpExporter(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+
+		interval := config.Interval
+		if interval <= 0 {
+			interval = defaultExportInterval
+		}
+		opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))))
+	}
+
+	if config.Prometheus {
+		registry := prometheus.NewRegistry()
+		exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create prometheus exporter: %w", err)
+		}
+		provider.gatherer = registry
+		opts = append(opts, sdkmetric.WithReader(exporter))
+	}
+
+	provider.MeterProvider = sdkmetric.NewMeterProvider(opts...)
+	return provider, nil
+}
+
+func otelResource(ctx context.Context, config OTELConfig) (*resource.Resource, error) {
+	instance := config.ServiceInstance
+	if instance == "" {
+		instance, _ = os.Hostname()
+	}
+
+	attributes := []resource.Option{
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithTelemetrySDK(),
+		// OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME are read first so that the config takes precedence
+		resource.WithFromEnv(),
+	}
+	if config.ServiceName != "" {
+		attributes = append(attributes, resource.WithAttributes(semconv.ServiceName(config.ServiceName)))
+	}
+	if config.ServiceVersion != "" {
+		attributes = append(attributes, resource.WithAttributes(semconv.ServiceVersion(config.ServiceVersion)))
+	}
+	if instance != "" {
+		attributes = append(attributes, resource.WithAttributes(semconv.ServiceInstanceID(instance)))
+	}
+
+	res, err := resource.New(ctx, attributes...)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+	return res, nil
+}
+
+// otlpExporter creates the exporter of the protocol, an endpoint with a scheme is used as a URL.
+func otlpExporter(ctx context.Context, config OTELConfig) (sdkmetric.Exporter, error) {
+	url := strings.Contains(config.Endpoint, "://")
+
+	var (
+		exporter sdkmetric.Exporter
+		err      error
+	)
+	switch config.Protocol {
+	case ProtocolGRPC:
+		var opts []otlpmetricgrpc.Option
+		switch {
+		case url:
+			opts = append(opts, otlpmetricgrpc.WithEndpointURL(config.Endpoint))
+		case config.Endpoint != "":
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		exporter, err = otlpmetricgrpc.New(ctx, opts...)
+	case ProtocolHTTP:
+		var opts []otlpmetrichttp.Option
+		switch {
+		case url:
+			opts = append(opts, otlpmetrichttp.WithEndpointURL(config.Endpoint))
+		case config.Endpoint != "":
+			opts = append(opts, otlpmetrichttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		exporter, err = otlpmetrichttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("invalid otlp protocol %q: must be %s or %s", config.Protocol, ProtocolGRPC, ProtocolHTTP)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp %s exporter: %w", config.Protocol, err)
+	}
+	return exporter, nil
+}
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpReceiver is an in-process OTLP receiver recording the exported metric names and resource attributes.
+type otlpReceiver struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+
+	mu       sync.Mutex
+	metrics  map[string]bool
+	resource map[string]string
+}
+
+func newOTLPReceiver() *otlpReceiver {
+	return &otlpReceiver{metrics: map[string]bool{}, resource: map[string]string{}}
+}
+
+func (o *otlpReceiver) Export(_ context.Context, request *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, resourceMetrics := range request.GetResourceMetrics() {
+		for _, attr := range resourceMetrics.GetResource().GetAttributes() {
+			o.resource[attr.GetKey()] = attr.GetValue().GetStringValue()
+		}
+		for _, scope := range resourceMetrics.GetScopeMetrics() {
+			for _, m := range scope.GetMetrics() {
+				o.metrics[m.GetName()] = true
+			}
+		}
+	}
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+func (o *otlpReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var request colmetricpb.ExportMetricsServiceRequest
+	if err := proto.Unmarshal(body, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, _ := o.Export(r.Context(), &request)
+	b, _ := proto.Marshal(response)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(b)
+}
+
+func (o *otlpReceiver) received(name string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.metrics[name]
+}
+
+func TestOTELProvider_OTLP(t *testing.T) {
+	receiver := newOTLPReceiver()
+
+	httpServer := httptest.NewServer(receiver)
+	t.Cleanup(httpServer.Close)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcServer := grpc.NewServer()
+	colmetricpb.RegisterMetricsServiceServer(grpcServer, receiver)
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	tests := map[string]OTELConfig{
+		"http": {Protocol: ProtocolHTTP, Endpoint: httpServer.URL + "/v1/metrics"},
+		"grpc": {Protocol: ProtocolGRPC, Endpoint: listener.Addr().String(), Insecure: true},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			config.ServiceName, config.ServiceVersion, config.ServiceInstance = "greeter", "1.2.3", "pod-1"
+			config.Interval = time.Hour
+
+			provider, err := NewOTELProvider(ctx, config)
+			require.NoError(t, err)
+
+			reporter := NewOTELReporter(WithOTELProvider(provider))
+			reporter.RegisterCounter("otlp_"+name+"_total", "test counter", "kind")
+			reporter.IncCounter("otlp_"+name+"_total", 1, "a")
+
+			require.NoError(t, provider.Shutdown(ctx), "shutting down pushes the last metrics")
+			assert.True(t, receiver.received("otlp_"+name+"_total"))
+
+			receiver.mu.Lock()
+			defer receiver.mu.Unlock()
+			assert.Equal(t, "greeter", receiver.resource["service.name"])
+			assert.Equal(t, "1.2.3", receiver.resource["service.version"])
+			assert.Equal(t, "pod-1", receiver.resource["service.instance.id"])
+		})
+	}
+}
+
+func TestOTELProvider_Prometheus(t *testing.T) {
+	provider, err := NewOTELProvider(context.Background(), OTELConfig{ServiceName: "greeter", Prometheus: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	reporter := NewOTELReporter(WithOTELProvider(provider))
+	reporter.RegisterCounter("pulled_total", "test counter", "kind")
+	reporter.IncCounter("pulled_total", 3, "a")
+
+	mux := http.NewServeMux()
+	reporter.Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `pulled_total{kind="a"`)
+}
+
+func TestOTELProvider_InvalidProtocol(t *testing.T) {
+	_, err := NewOTELProvider(context.Background(), OTELConfig{Protocol: "udp"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// Defaults of the base-2 exponential histograms summaries are recorded with, the ones recommended by the
+// OpenTelemetry specification.
+const (
+	defaultExponentialMaxSize  = 160
+	defaultExponentialMaxScale = 20
+)
+
+// OTELView customizes how the metrics matching Instrument are aggregated and exported.
+type OTELView struct {
+	// Instrument is the name of the metric, * matches any sequence of characters.
+	Instrument string
+	// Rename exports the metric under another name.
+	Rename string
+	// Buckets records a histogram or summary with these explicit bucket boundaries.
+	Buckets []float64
+	// Exponential records a histogram with a base-2 exponential histogram of at most MaxSize buckets per sign and a
+	// scale of at most MaxScale, which default to 160 and 20. Summaries are exponential unless Buckets is set or the
+	// provider has the Prometheus pull exporter, as its buckets are only scraped as Prometheus native histograms.
+	Exponential bool
+	MaxSize     int32
+	MaxScale    int32
+	// Attributes keeps only these attributes, an empty list keeps them all.
+	Attributes []string
+}
+
+// validate checks the view can be applied.
+func (v OTELView) validate() error {
+	if v.Instrument == "" {
+		return fmt.Errorf("invalid metrics view: instrument is required")
+	}
+	if _, err := path.Match(v.Instrument, ""); err != nil {
+		return fmt.Errorf("invalid metrics view instrument %q: %w", v.Instrument, err)
+	}
+	if len(v.Buckets) > 0 && v.Exponential {
+		return fmt.Errorf("invalid metrics view for %q: buckets and exponential are exclusive", v.Instrument)
+	}
+	if !sort.Float64sAreSorted(v.Buckets) {
+		return fmt.Errorf("invalid metrics view for %q: buckets must be sorted", v.Instrument)
+	}
+	if v.MaxSize < 0 || v.MaxScale < -10 || v.MaxScale > 20 {
+		return fmt.Errorf("invalid metrics view for %q: max size must be positive and max scale between -10 and 20", v.Instrument)
+	}
+	return nil
+}
+
+// views applies the configured views, and records the summaries of the reporter with exponential histograms unless
+// explicitSummaries is set.
+type views struct {
+	config    []OTELView
+	summaries sync.Map
+	// explicitSummaries keeps the explicit buckets of summaries for the Prometheus pull exporter, whose text format
+	// exposes no buckets of exponential histograms
+	explicitSummaries bool
+}
+
+// summary marks the instrument of the reporter as a summary, which must be done before creating it.
+func (v *views) summary(name string) {
+	v.summaries.Store(name, struct{}{})
+}
+
+// match returns the first configured view of the instrument.
+func (v *views) match(name string) (OTELView, bool) {
+	for _, view := range v.config {
+		if ok, _ := path.Match(view.Instrument, name); ok {
+			return view, true
+		}
+	}
+	return OTELView{}, false
+}
+
+// stream is the sdk view applying the configuration to the instruments of the reporter.
+func (v *views) stream(instrument sdkmetric.Instrument) (sdkmetric.Stream, bool) {
+	if instrument.Scope.Name != _meterName {
+		return sdkmetric.Stream{}, false
+	}
+
+	view, configured := v.match(instrument.Name)
+	_, summary := v.summaries.Load(instrument.Name)
+	if !configured && !summary {
+		return sdkmetric.Stream{}, false
+	}
+
+	stream := sdkmetric.Stream{Name: instrument.Name, Description: instrument.Description, Unit: instrument.Unit}
+	if view.Rename != "" {
+		stream.Name = view.Rename
+	}
+	if len(view.Attributes) > 0 {
+		stream.AttributeFilter = attribute.NewAllowKeysFilter(toKeys(view.Attributes)...)
+	}
+	if instrument.Kind == sdkmetric.InstrumentKindHistogram {
+		switch {
+		case len(view.Buckets) > 0:
+			stream.Aggregation = sdkmetric.AggregationExplicitBucketHistogram{Boundaries: view.Buckets}
+		case view.Exponential || summary && !v.explicitSummaries:
+			maxSize, maxScale := view.exponential()
+			stream.Aggregation = sdkmetric.AggregationBase2ExponentialHistogram{MaxSize: maxSize, MaxScale: maxScale}
+		}
+	}
+	return stream, true
+}
+
+// describe reports how the summary or histogram of the reporter with the default buckets is materialized.
+func (v *views) describe(name string, summary bool, buckets []float64) string {
+	view, _ := v.match(name)
+
+	var description string
+	switch {
+	case len(view.Buckets) > 0:
+		description = explicitHistogram(view.Buckets)
+	case view.Exponential || summary && !v.explicitSummaries:
+		maxSize, maxScale := view.exponential()
+		description = fmt.Sprintf("base-2 exponential histogram, max %d buckets, max scale %d", maxSize, maxScale)
+		if v.explicitSummaries {
+			description += ", buckets scraped as native histograms only"
+		}
+	default:
+		description = explicitHistogram(buckets)
+	}
+	if view.Rename != "" {
+		description += ", exported as " + view.Rename
+	}
+	if len(view.Attributes) > 0 {
+		description += ", keeping " + strings.Join(view.Attributes, ", ")
+	}
+	return description
+}
+
+// exponential returns the maximum size and scale of the exponential histogram of the view.
+func (v OTELView) exponential() (int32, int32) {
+	maxSize, maxScale := v.MaxSize, v.MaxScale
+	if maxSize == 0 {
+		maxSize = defaultExponentialMaxSize
+	}
+	if maxScale == 0 {
+		maxScale = defaultExponentialMaxScale
+	}
+	return maxSize, maxScale
+}
+
+func explicitHistogram(buckets []float64) string {
+	return fmt.Sprintf("explicit bucket histogram, %d buckets", len(buckets))
+}
+
+func toKeys(names []string) []attribute.Key {
+	keys := make([]attribute.Key, len(names))
+	for i, name := range names {
+		keys[i] = attribute.Key(name)
+	}
+	return keys
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// family returns the gathered metric family of the name.
+func family(t *testing.T, provider *OTELProvider, name string) *dto.MetricFamily {
+	t.Helper()
+
+	families, err := provider.gatherer.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() == name {
+			return f
+		}
+	}
+	require.Failf(t, "metric not gathered", "%s", name)
+	return nil
+}
+
+func TestOTELReporter_Views(t *testing.T) {
+	provider, err := NewOTELProvider(context.Background(), OTELConfig{
+		Prometheus: true,
+		Views: []OTELView{
+			{Instrument: "view_latency", Buckets: []float64{1, 2, 4}},
+			{Instrument: "view_size*", Rename: "view_bytes", Exponential: true, MaxSize: 20, Attributes: []string{"method"}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	reporter := NewOTELReporter(WithOTELProvider(provider))
+	reporter.RegisterSummary("view_duration", "summary", map[float64]float64{0.5: 0.05}, "method")
+	reporter.RegisterSummary("view_latency", "summary with buckets", nil, "method")
+	reporter.RegisterHistogram("view_size", "renamed histogram", nil, "method", "path")
+	reporter.RegisterHistogram("view_default", "histogram", []float64{10, 20}, "method")
+
+	for _, name := range []string{"view_duration", "view_latency", "view_default"} {
+		reporter.ObserveHistogram(name, 3, "GET")
+		reporter.ObserveSummary(name, 3, "GET")
+	}
+	reporter.ObserveHistogram("view_size", 3, "GET", "/a")
+	reporter.ObserveHistogram("view_size", 5, "GET", "/b")
+
+	duration := family(t, provider, "view_duration").GetMetric()[0].GetHistogram()
+	assert.Len(t, duration.GetBucket(), len(defaultBuckets), "summaries keep their buckets for the pull exporter")
+
+	latency := family(t, provider, "view_latency").GetMetric()[0].GetHistogram()
+	assert.Len(t, latency.GetBucket(), 3, "buckets of the view")
+
+	assert.Len(t, family(t, provider, "view_default").GetMetric()[0].GetHistogram().GetBucket(), 2)
+
+	bytes := family(t, provider, "view_bytes").GetMetric()
+	require.Len(t, bytes, 1, "the path attribute is dropped")
+	assert.Equal(t, uint64(2), bytes[0].GetHistogram().GetSampleCount())
+
+	definitions := reporter.GetMetricsDefinition()
+	assert.Equal(t, "explicit bucket histogram, 15 buckets", definitions["view_duration"].Aggregation)
+	assert.Equal(t, "explicit bucket histogram, 3 buckets", definitions["view_latency"].Aggregation)
+	assert.Equal(t, "base-2 exponential histogram, max 20 buckets, max scale 20, buckets scraped as native histograms only, "+
+		"exported as view_bytes, keeping method", definitions["view_size"].Aggregation)
+	assert.Equal(t, "explicit bucket histogram, 2 buckets", definitions["view_default"].Aggregation)
+
+	mux := http.NewServeMux()
+	reporter.Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics/docs", nil))
+	assert.Contains(t, rec.Body.String(), "| view_latency | summary with buckets | summary | explicit bucket histogram, 3 buckets | method |")
+}
+
+func TestOTELReporter_PushSummary(t *testing.T) {
+	provider, err := NewOTELProvider(context.Background(), OTELConfig{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	reporter := NewOTELReporter(WithOTELProvider(provider))
+	reporter.RegisterSummary("push_duration", "summary", nil)
+
+	assert.Equal(t, "base-2 exponential histogram, max 160 buckets, max scale 20",
+		reporter.GetMetricsDefinition()["push_duration"].Aggregation, "summaries are exponential without the pull exporter")
+}
+
+func TestOTELReporter_GlobalProviderSummary(t *testing.T) {
+	reporter := NewOTELReporter()
+	reporter.RegisterSummary("global_duration", "summary", nil)
+
+	assert.Equal(t, "explicit bucket histogram, 15 buckets", reporter.GetMetricsDefinition()["global_duration"].Aggregation)
+}
+
+func TestOTELProvider_InvalidView(t *testing.T) {
+	views := map[string]OTELView{
+		"no instrument": {Buckets: []float64{1}},
+		"exclusive":     {Instrument: "a", Buckets: []float64{1}, Exponential: true},
+		"unsorted":      {Instrument: "a", Buckets: []float64{2, 1}},
+		"pattern":       {Instrument: "a["},
+	}
+
+	for name, view := range views {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewOTELProvider(context.Background(), OTELConfig{Views: []OTELView{view}})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+const (
+	_droppedObservations = "metrics_dropped_observations_total"
+	_droppedDescription  = "Metric observations dropped because the metric is unknown or the labels do not match"
+
+	// ReasonUnknownMetric labels observations of a metric that was not registered with the kind observed.
+	ReasonUnknownMetric = "unknown_metric"
+	// ReasonLabelMismatch labels observations with a different number of label values than the metric was registered with.
+	ReasonLabelMismatch = "label_mismatch"
+)
+
+var _droppedLabels = []string{"metric", "reason"}
+
+// Policy decides what a reporter does with an observation it cannot record.
+type Policy int
+
+const (
+	// Lenient drops the observation, counts it in metrics_dropped_observations_total and logs the first drop of every
+	// metric.
+	Lenient Policy = iota
+	// Strict panics, so that tests fail on the mistake.
+	Strict
+)
+
+// ParsePolicy parses "lenient" or "strict", an empty string is lenient.
+func ParsePolicy(s string) (Policy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "lenient":
+		return Lenient, nil
+	case "strict":
+		return Strict, nil
+	default:
+		return Lenient, fmt.Errorf("invalid metrics policy %q: must be lenient or strict", s)
+	}
+}
+
+// dropper applies the policy to observations a reporter cannot record.
+type dropper struct {
+	policy Policy
+	count  func(metric, reason string)
+	logged sync.Map
+}
+
+// drop handles an observation of the metric that cannot be recorded for the reason.
+func (d *dropper) drop(name, reason string, labels []string) {
+	if d.policy == Strict {
+		panic(fmt.Sprintf("metrics: cannot record %s with labels %q: %s", name, labels, reason))
+	}
+
+	d.count(name, reason)
+	if _, logged := d.logged.LoadOrStore(name, struct{}{}); !logged {
+		slog.Warn("dropped metric observation",
+			slog.String("metric", name),
+			slog.String("reason", reason),
+			slog.Any("labels", labels),
+		)
+	}
+}
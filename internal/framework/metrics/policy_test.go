@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dropped returns the value of metrics_dropped_observations_total for the metric and reason.
+func dropped(t *testing.T, gatherer prometheus.Gatherer, metric, reason string) float64 {
+	t.Helper()
+
+	families, err := gatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != _droppedObservations {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["metric"] == metric && labels["reason"] == reason {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestPrometheusReporter_Lenient(t *testing.T) {
+	reporter := NewPrometheusReporter()
+	reporter.RegisterCounter("policy_lenient_total", "test counter", "kind")
+
+	reporter.IncCounter("policy_lenient_total", 1, "a", "b")
+	reporter.IncCounter("policy_lenient_total", 1, "a", "b")
+	reporter.ObserveHistogram("policy_lenient_total", 1, "a")
+	reporter.SetGauge("policy_unknown", 1)
+
+	assert.Equal(t, 2.0, dropped(t, reporter.Gatherer(), "policy_lenient_total", ReasonLabelMismatch))
+	assert.Equal(t, 1.0, dropped(t, reporter.Gatherer(), "policy_lenient_total", ReasonUnknownMetric), "a counter is not a histogram")
+	assert.Equal(t, 1.0, dropped(t, reporter.Gatherer(), "policy_unknown", ReasonUnknownMetric))
+	assert.Contains(t, reporter.GetMetricsDefinition(), _droppedObservations)
+}
+
+func TestReporters_Strict(t *testing.T) {
+	reporters := map[string]Reporter{
+		"prometheus": NewPrometheusReporter(WithPolicy(Strict)),
+		"otel":       NewOTELReporter(WithPolicy(Strict)),
+	}
+
+	for name, reporter := range reporters {
+		t.Run(name, func(t *testing.T) {
+			reporter.RegisterCounter("policy_strict_"+name+"_total", "test counter", "kind")
+
+			assert.NotPanics(t, func() { reporter.IncCounter("policy_strict_"+name+"_total", 1, "a") })
+			assert.Panics(t, func() { reporter.IncCounter("policy_strict_"+name+"_total", 1) })
+			assert.Panics(t, func() { reporter.ObserveSummary("policy_strict_unknown", 1) })
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("Strict")
+	require.NoError(t, err)
+	assert.Equal(t, Strict, policy)
+
+	policy, err = ParsePolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, Lenient, policy)
+
+	_, err = ParsePolicy("loud")
+	assert.Error(t, err)
+}
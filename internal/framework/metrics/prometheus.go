@@ -1,37 +1,97 @@
 package metrics
 
 import (
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// PrometheusReporter records metrics in its own prometheus.Registry, served on /metrics by Routes.
 type PrometheusReporter struct {
 	sync.RWMutex
+	registry             *prometheus.Registry
+	namespace            string
+	subsystem            string
+	constLabels          prometheus.Labels
 	metricRegistry       map[string]MetricDefinition
 	counterDefinitions   map[string]*prometheus.CounterVec
 	gaugeDefinitions     map[string]*prometheus.GaugeVec
 	summaryDefinitions   map[string]*prometheus.SummaryVec
 	histogramDefinitions map[string]*prometheus.HistogramVec
+	dropper              *dropper
 }
 
-func NewPrometheusReporter() *PrometheusReporter {
-	return &PrometheusReporter{
+// NewPrometheusReporter creates a reporter with a registry of its own, so that any number of reporters can coexist. The
+// Go runtime, process and build info collectors are only registered when enabled by an option.
+func NewPrometheusReporter(opts ...Option) *PrometheusReporter {
+	o := newOptions(opts)
+
+	registry := prometheus.NewRegistry()
+	if o.goCollector {
+		registry.MustRegister(collectors.NewGoCollector())
+	}
+	if o.processCollector {
+		registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+	if o.buildInfoCollector {
+		registry.MustRegister(collectors.NewBuildInfoCollector())
+	}
+
+	p := &PrometheusReporter{
+		registry:             registry,
+		namespace:            o.namespace,
+		subsystem:            o.subsystem,
+		constLabels:          o.constLabels,
 		metricRegistry:       make(map[string]MetricDefinition),
 		counterDefinitions:   make(map[string]*prometheus.CounterVec),
 		gaugeDefinitions:     make(map[string]*prometheus.GaugeVec),
 		summaryDefinitions:   make(map[string]*prometheus.SummaryVec),
 		histogramDefinitions: make(map[string]*prometheus.HistogramVec),
 	}
+
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts(p.opts(_droppedObservations, _droppedDescription)), _droppedLabels)
+	registry.MustRegister(dropped)
+	p.registerMetrics(_droppedObservations, _droppedDescription, metricTypeCounter, _droppedLabels)
+	p.dropper = &dropper{
+		policy: o.policy,
+		count:  func(metric, reason string) { dropped.WithLabelValues(metric, reason).Inc() },
+	}
+	return p
+}
+
+// Gatherer returns the registry the metrics of the reporter are recorded in.
+func (p *PrometheusReporter) Gatherer() prometheus.Gatherer {
+	return p.registry
+}
+
+func (p *PrometheusReporter) opts(name, description string) prometheus.Opts {
+	return prometheus.Opts{
+		Namespace:   p.namespace,
+		Subsystem:   p.subsystem,
+		Name:        name,
+		Help:        description,
+		ConstLabels: p.constLabels,
+	}
 }
 
 func (p *PrometheusReporter) registerMetrics(name string, description string, kind string, labels []string) {
+	p.registerAggregation(name, description, kind, "", labels)
+}
+
+func (p *PrometheusReporter) registerAggregation(name string, description string, kind string, aggregation string, labels []string) {
 	p.Lock()
 	p.metricRegistry[name] = MetricDefinition{
+		Name:        prometheus.BuildFQName(p.namespace, p.subsystem, name),
 		Kind:        kind,
 		Description: description,
+		Aggregation: aggregation,
 		Labels:      labels,
 		labelCount:  len(labels),
 	}
@@ -41,12 +101,11 @@ func (p *PrometheusReporter) registerMetrics(name string, description string, ki
 func (p *PrometheusReporter) RegisterCounter(name string, description string, labels ...string) {
 	sanitize(labels)
 
-	opts := prometheus.CounterOpts{Name: name, Help: description}
-	counter := prometheus.NewCounterVec(opts, labels)
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts(p.opts(name, description)), labels)
 	p.registerMetrics(name, description, metricTypeCounter, labels)
 
 	p.Lock()
-	prometheus.MustRegister(counter)
+	p.registry.MustRegister(counter)
 	p.counterDefinitions[name] = counter
 	p.Unlock()
 }
@@ -54,12 +113,12 @@ func (p *PrometheusReporter) RegisterCounter(name string, description string, la
 func (p *PrometheusReporter) RegisterGauge(name string, description string, labels ...string) {
 	sanitize(labels)
 
-	opts := prometheus.GaugeOpts{Name: name, Help: description}
+	opts := prometheus.GaugeOpts(p.opts(name, description))
 	gauge := prometheus.NewGaugeVec(opts, labels)
 	p.registerMetrics(name, description, metricTypeGauge, labels)
 
 	p.Lock()
-	prometheus.MustRegister(gauge)
+	p.registry.MustRegister(gauge)
 	p.gaugeDefinitions[name] = gauge
 	p.Unlock()
 }
@@ -67,12 +126,19 @@ func (p *PrometheusReporter) RegisterGauge(name string, description string, labe
 func (p *PrometheusReporter) RegisterSummary(name string, description string, quantiles map[float64]float64, labels ...string) {
 	sanitize(labels)
 
-	opts := prometheus.SummaryOpts{Name: name, Help: description, Objectives: quantiles}
+	opts := prometheus.SummaryOpts{
+		Namespace:   p.namespace,
+		Subsystem:   p.subsystem,
+		Name:        name,
+		Help:        description,
+		ConstLabels: p.constLabels,
+		Objectives:  quantiles,
+	}
 	summary := prometheus.NewSummaryVec(opts, labels)
-	p.registerMetrics(name, description, metricTypeSummary, labels)
+	p.registerAggregation(name, description, metricTypeSummary, summaryQuantiles(quantiles), labels)
 
 	p.Lock()
-	prometheus.MustRegister(summary)
+	p.registry.MustRegister(summary)
 	p.summaryDefinitions[name] = summary
 	p.Unlock()
 }
@@ -84,70 +150,75 @@ func (p *PrometheusReporter) RegisterHistogram(name string, description string,
 		buckets = prometheus.DefBuckets
 	}
 
-	opts := prometheus.HistogramOpts{Name: name, Help: description, Buckets: buckets}
+	opts := prometheus.HistogramOpts{
+		Namespace:   p.namespace,
+		Subsystem:   p.subsystem,
+		Name:        name,
+		Help:        description,
+		ConstLabels: p.constLabels,
+		Buckets:     buckets,
+	}
 	histogram := prometheus.NewHistogramVec(opts, labels)
-	p.registerMetrics(name, description, metricTypeHistogram, labels)
+	p.registerAggregation(name, description, metricTypeHistogram, explicitHistogram(buckets), labels)
 
 	p.Lock()
-	prometheus.MustRegister(histogram)
+	p.registry.MustRegister(histogram)
 	p.histogramDefinitions[name] = histogram
 	p.Unlock()
 }
 
 func (p *PrometheusReporter) IncCounter(name string, value float64, labels ...string) {
 	p.RLock()
-	if metric, ok := p.counterDefinitions[name]; ok {
-		labelCount := p.metricRegistry[name].labelCount
-		if labelCount == len(labels) {
-			metric.WithLabelValues(labels...).Add(value)
-		} else {
-			// Error
-		}
+	defer p.RUnlock()
+
+	if metric, ok := p.counterDefinitions[name]; p.accepts(name, ok, labels) {
+		metric.WithLabelValues(labels...).Add(value)
 	}
-	p.RUnlock()
 }
 
 func (p *PrometheusReporter) SetGauge(name string, value float64, labels ...string) {
 	p.RLock()
-	if metric, ok := p.gaugeDefinitions[name]; ok {
-		labelCount := p.metricRegistry[name].labelCount
-		if labelCount == len(labels) {
-			metric.WithLabelValues(labels...).Set(value)
-		}
-	} else {
-		// Error
+	defer p.RUnlock()
+
+	if metric, ok := p.gaugeDefinitions[name]; p.accepts(name, ok, labels) {
+		metric.WithLabelValues(labels...).Set(value)
 	}
-	p.RUnlock()
 }
 
 func (p *PrometheusReporter) ObserveSummary(name string, value float64, labels ...string) {
 	p.RLock()
-	if metric, ok := p.summaryDefinitions[name]; ok {
-		labelCount := p.metricRegistry[name].labelCount
-		if labelCount == len(labels) {
-			metric.WithLabelValues(labels...).Observe(value)
-		}
-	} else {
-		// Error
+	defer p.RUnlock()
+
+	if metric, ok := p.summaryDefinitions[name]; p.accepts(name, ok, labels) {
+		metric.WithLabelValues(labels...).Observe(value)
 	}
-	p.RUnlock()
 }
 
 func (p *PrometheusReporter) ObserveHistogram(name string, value float64, labels ...string) {
 	p.RLock()
-	if metric, ok := p.histogramDefinitions[name]; ok {
-		labelCount := p.metricRegistry[name].labelCount
-		if labelCount == len(labels) {
-			metric.WithLabelValues(labels...).Observe(value)
-		}
-	} else {
-		// Error
+	defer p.RUnlock()
+
+	if metric, ok := p.histogramDefinitions[name]; p.accepts(name, ok, labels) {
+		metric.WithLabelValues(labels...).Observe(value)
 	}
-	p.RUnlock()
+}
+
+// accepts reports whether the metric is registered with as many labels as there are values, dropping the observation if
+// not. It must be called with the read lock held.
+func (p *PrometheusReporter) accepts(name string, registered bool, labels []string) bool {
+	if !registered {
+		p.dropper.drop(name, ReasonUnknownMetric, labels)
+		return false
+	}
+	if p.metricRegistry[name].labelCount != len(labels) {
+		p.dropper.drop(name, ReasonLabelMismatch, labels)
+		return false
+	}
+	return true
 }
 
 func (p *PrometheusReporter) Routes(mux *http.ServeMux) {
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{}))
 	mux.HandleFunc("/metrics/docs", MetricDocs(p))
 }
 
@@ -162,3 +233,16 @@ func (p *PrometheusReporter) GetMetricsDefinition() map[string]MetricDefinition
 	p.RUnlock()
 	return metrics
 }
+
+// summaryQuantiles describes a summary with the quantiles.
+func summaryQuantiles(quantiles map[float64]float64) string {
+	if len(quantiles) == 0 {
+		return "summary, count and sum only"
+	}
+
+	values := make([]string, 0, len(quantiles))
+	for _, quantile := range slices.Sorted(maps.Keys(quantiles)) {
+		values = append(values, strconv.FormatFloat(quantile, 'g', -1, 64))
+	}
+	return "summary, quantiles " + strings.Join(values, ", ")
+}
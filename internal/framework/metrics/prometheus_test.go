@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, reporter *PrometheusReporter) string {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	reporter.Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestPrometheusReporter_Instances(t *testing.T) {
+	first, second := NewPrometheusReporter(), NewPrometheusReporter()
+
+	assert.NotPanics(t, func() {
+		first.RegisterCounter("requests_total", "test counter")
+		second.RegisterCounter("requests_total", "test counter")
+	})
+
+	first.IncCounter("requests_total", 2)
+	second.IncCounter("requests_total", 1)
+
+	assert.Contains(t, scrape(t, first), "requests_total 2")
+	assert.Contains(t, scrape(t, second), "requests_total 1")
+	assert.NotContains(t, scrape(t, first), "go_goroutines", "runtime collectors are opt-in")
+}
+
+func TestPrometheusReporter_Options(t *testing.T) {
+	reporter := NewPrometheusReporter(
+		WithNamespace("shop"),
+		WithSubsystem("api"),
+		WithConstLabels(map[string]string{"service": "greeter", "version": "1.2.3", "pod": ""}),
+		WithGoCollector(),
+		WithProcessCollector(),
+		WithBuildInfoCollector(),
+	)
+	reporter.RegisterHistogram("latency", "test histogram", []float64{1, 10}, "path")
+	reporter.ObserveHistogram("latency", 5, "/hello")
+
+	body := scrape(t, reporter)
+	assert.Contains(t, body, `shop_api_latency_bucket{path="/hello",service="greeter",version="1.2.3",le="10"} 1`)
+	assert.Contains(t, body, "go_goroutines")
+	assert.Contains(t, body, "go_build_info")
+	assert.NotContains(t, body, `pod=`, "empty labels are left out")
+
+	assert.Equal(t, "shop_api_latency", reporter.GetMetricsDefinition()["latency"].Name)
+	mux := http.NewServeMux()
+	reporter.Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics/docs", nil))
+	assert.Contains(t, rec.Body.String(), "| shop_api_latency | test histogram |", "the docs list the exposed names")
+}
+
+func TestPrometheusReporter_Aggregation(t *testing.T) {
+	reporter := NewPrometheusReporter()
+	reporter.RegisterSummary("duration", "test summary", map[float64]float64{0.99: 0.001, 0.5: 0.05})
+	reporter.RegisterSummary("size", "test summary", nil)
+	reporter.RegisterHistogram("latency", "test histogram", nil)
+
+	definitions := reporter.GetMetricsDefinition()
+	assert.Equal(t, "summary, quantiles 0.5, 0.99", definitions["duration"].Aggregation)
+	assert.Equal(t, "summary, count and sum only", definitions["size"].Aggregation)
+	assert.Equal(t, "explicit bucket histogram, 11 buckets", definitions["latency"].Aggregation)
+}
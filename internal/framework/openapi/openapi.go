@@ -0,0 +1,124 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Version is the OpenAPI specification version of the documents built by this package.
+const Version = "3.1.0"
+
+var pathParam = regexp.MustCompile(`\{([^}.]+)(\.\.\.)?}`)
+
+// Document is an OpenAPI 3.1 document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	// types holds the type of every component schema, to tell apart types of different packages sharing a name
+	types map[string]reflect.Type
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// PathItem holds the operations of a path keyed by lower case http method.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// NewDocument creates an empty document.
+func NewDocument(title, version string) *Document {
+	return &Document{
+		OpenAPI:    Version,
+		Info:       Info{Title: title, Version: version},
+		Paths:      map[string]*PathItem{},
+		Components: Components{Schemas: map[string]*Schema{}},
+	}
+}
+
+// AddOperation adds the operation for an http method and path, such as "GET" and "/users/{id}".
+func (d *Document) AddOperation(method, path string, operation *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	(*item)[strings.ToLower(method)] = operation
+}
+
+// PathParameters returns the names of the wildcards of a net/http path pattern in order, "{rest...}" yielding "rest".
+func PathParameters(path string) []string {
+	var names []string
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// Path converts a net/http path pattern to an OpenAPI path, removing the "..." of remainder wildcards and the "{$}" end
+// anchor.
+func Path(pattern string) string {
+	pattern = strings.ReplaceAll(pattern, "{$}", "")
+	return pathParam.ReplaceAllString(pattern, "{$1}")
+}
+
+// OperationID derives an operation id such as "getUsersById" from a method and path.
+func OperationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			id.WriteString("By")
+			segment = strings.TrimSuffix(strings.TrimSuffix(name, "}"), "...")
+		}
+
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			id.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+
+	return id.String()
+}
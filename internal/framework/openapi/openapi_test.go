@@ -0,0 +1,90 @@
+package openapi
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type node struct {
+	Name     string            `json:"name" validate:"required,min=1,max=20"`
+	Kind     string            `json:"kind,omitempty" validate:"oneof=leaf branch"`
+	Email    string            `json:"email" validate:"email"`
+	Weight   float64           `json:"weight" validate:"min=0"`
+	Tags     []string          `json:"tags" validate:"max=3,dive,len=2"`
+	Labels   map[string]string `json:"labels"`
+	Created  time.Time         `json:"created"`
+	Children []*node           `json:"children"`
+	Secret   string            `json:"-"`
+	ID       string            `path:"id"`
+	embedded
+}
+
+type embedded struct {
+	Version int `json:"version"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	document := NewDocument("test", "1")
+
+	ref := document.SchemaFor(reflect.TypeFor[*node]())
+	assert.Equal(t, "#/components/schemas/node", ref.Ref)
+
+	schema := document.Components.Schemas["node"]
+	require.NotNil(t, schema)
+	assert.Equal(t, []string{"name"}, schema.Required)
+	assert.ElementsMatch(t, []string{"name", "kind", "email", "weight", "tags", "labels", "created", "children", "version"}, keys(schema.Properties))
+
+	assert.Equal(t, 1, *schema.Properties["name"].MinLength)
+	assert.Equal(t, 20, *schema.Properties["name"].MaxLength)
+	assert.Equal(t, []any{"leaf", "branch"}, schema.Properties["kind"].Enum)
+	assert.Equal(t, "email", schema.Properties["email"].Format)
+	assert.Equal(t, 0.0, *schema.Properties["weight"].Minimum)
+	assert.Equal(t, 3, *schema.Properties["tags"].MaxItems)
+	assert.Equal(t, 2, *schema.Properties["tags"].Items.MinLength)
+	assert.Equal(t, "string", schema.Properties["labels"].AdditionalProperties.Type)
+	assert.Equal(t, "date-time", schema.Properties["created"].Format)
+	assert.Equal(t, "#/components/schemas/node", schema.Properties["children"].Items.Ref)
+	assert.Equal(t, "int64", schema.Properties["version"].Format)
+}
+
+func TestSchemaFor_SameName(t *testing.T) {
+	type node struct {
+		Value int `json:"value"`
+	}
+	document := NewDocument("test", "1")
+
+	assert.Equal(t, "#/components/schemas/Decoder", document.SchemaFor(reflect.TypeFor[json.Decoder]()).Ref)
+	assert.Equal(t, "#/components/schemas/xml.Decoder", document.SchemaFor(reflect.TypeFor[xml.Decoder]()).Ref,
+		"a type of another package is qualified by its package")
+	assert.Equal(t, "#/components/schemas/Decoder", document.SchemaFor(reflect.TypeFor[json.Decoder]()).Ref)
+
+	assert.Equal(t, "#/components/schemas/node", document.SchemaFor(reflect.TypeFor[*node]()).Ref)
+	assert.Equal(t, "#/components/schemas/node_2", document.SchemaFor(reflect.TypeFor[*nodeAlias]()).Ref,
+		"a type of the same package and name is numbered")
+	assert.Contains(t, document.Components.Schemas["node"].Properties, "value")
+	assert.Contains(t, document.Components.Schemas["node_2"].Properties, "children")
+}
+
+// nodeAlias is the package level node, which has the same name as the node declared in TestSchemaFor_SameName.
+type nodeAlias = node
+
+func TestPath(t *testing.T) {
+	assert.Equal(t, "/files/{path}", Path("/files/{path...}"))
+	assert.Equal(t, "/", Path("/{$}"))
+	assert.Equal(t, []string{"store", "path"}, PathParameters("/stores/{store}/files/{path...}"))
+	assert.Equal(t, "getStoresByStoreFilesByPath", OperationID("GET", "/stores/{store}/files/{path}"))
+}
+
+func keys[V any](m map[string]V) []string {
+	var k []string
+	for key := range m {
+		k = append(k, key)
+	}
+	return k
+}
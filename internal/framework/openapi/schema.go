@@ -0,0 +1,368 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/taylorono/go-webservice/internal/framework/validation"
+)
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	rawMessageType      = reflect.TypeFor[json.RawMessage]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	invalidNameChars    = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	parameterSourceTags = []string{"path", "query", "header"}
+)
+
+// Schema is a JSON Schema as used by OpenAPI 3.1.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+// SchemaFor returns the schema of values of type t as encoded by encoding/json. Named structs are added to the document
+// components and referenced, fields tagged with path, query or header are left out as they are not part of the body, and
+// validate tags are translated to the matching constraints.
+func (d *Document) SchemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: ptr(0.0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.SchemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.SchemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+
+		name := d.componentName(t)
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// reserve the name first so recursive types refer to themselves
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	// interfaces and other kinds accept any value
+	return &Schema{}
+}
+
+// componentName returns the name of the component schema of the named struct t. It is the name of the type unless
+// another type already has it, then the name qualified by the package name, by the package path, and finally numbered
+// for types declared in different functions of the same package.
+func (d *Document) componentName(t reflect.Type) string {
+	if d.types == nil {
+		d.types = map[string]reflect.Type{}
+	}
+
+	candidates := []string{t.Name()}
+	if existing, ok := d.types[t.Name()]; ok && existing.PkgPath() != t.PkgPath() {
+		// qualify by package only when that tells the types apart
+		candidates = append(candidates, path.Base(t.PkgPath())+"."+t.Name(), t.PkgPath()+"."+t.Name())
+	}
+	for i := 2; ; i++ {
+		for _, candidate := range candidates {
+			name := invalidNameChars.ReplaceAllString(candidate, "_")
+			existing, ok := d.types[name]
+			if ok && existing == t {
+				return name
+			}
+			if _, taken := d.Components.Schemas[name]; !ok && !taken {
+				d.types[name] = t
+				return name
+			}
+		}
+		candidates = []string{t.Name() + "_" + strconv.Itoa(i)}
+	}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	d.addFields(schema, t)
+	return schema
+}
+
+func (d *Document) addFields(schema *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if (!field.IsExported() && !field.Anonymous) || isParameter(field) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		// embedded structs without a json name are flattened like encoding/json does
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			d.addFields(schema, fieldType)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		property := d.SchemaFor(field.Type)
+		rules, dive := validation.ParseTag(field.Tag.Get("validate"))
+		for _, rule := range rules {
+			if rule.Name == "required" {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		constrain(property, fieldType, rules)
+
+		if dive != nil {
+			switch {
+			case property.Items != nil:
+				constrain(property.Items, fieldType.Elem(), dive)
+			case property.AdditionalProperties != nil:
+				constrain(property.AdditionalProperties, fieldType.Elem(), dive)
+			}
+		}
+
+		schema.Properties[name] = property
+	}
+}
+
+// constrain applies the validate rules that have a JSON Schema equivalent.
+func constrain(schema *Schema, t reflect.Type, rules []validation.Rule) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	for _, rule := range rules {
+		switch rule.Name {
+		case "min", "max", "len":
+			limit, err := strconv.ParseFloat(rule.Param, 64)
+			if err != nil {
+				continue
+			}
+			setLimit(schema, t, rule.Name, limit)
+		case "oneof":
+			for _, option := range strings.Fields(rule.Param) {
+				schema.Enum = append(schema.Enum, enumValue(schema.Type, option))
+			}
+		case "email":
+			schema.Format = "email"
+		case "regex":
+			schema.Pattern = rule.Param
+		}
+	}
+}
+
+func setLimit(schema *Schema, t reflect.Type, name string, limit float64) {
+	var minimum, maximum **int
+	switch t.Kind() {
+	case reflect.String:
+		minimum, maximum = &schema.MinLength, &schema.MaxLength
+	case reflect.Slice, reflect.Array, reflect.Map:
+		minimum, maximum = &schema.MinItems, &schema.MaxItems
+	default:
+		if name != "max" {
+			schema.Minimum = ptr(limit)
+		}
+		if name != "min" {
+			schema.Maximum = ptr(limit)
+		}
+		return
+	}
+
+	if name != "max" {
+		*minimum = ptr(int(limit))
+	}
+	if name != "min" {
+		*maximum = ptr(int(limit))
+	}
+}
+
+func enumValue(schemaType, option string) any {
+	switch schemaType {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(option, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(option); err == nil {
+			return b
+		}
+	}
+	return option
+}
+
+// Parameters returns the path, query and header parameters described by the path, query and header struct tags of t, a
+// request type of web.Handle.
+func (d *Document) Parameters(t reflect.Type) []*Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var parameters []*Parameter
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			parameters = append(parameters, d.Parameters(field.Type)...)
+			continue
+		}
+
+		for _, in := range parameterSourceTags {
+			name := field.Tag.Get(in)
+			if name == "" {
+				continue
+			}
+
+			rules, dive := validation.ParseTag(field.Tag.Get("validate"))
+			schema := d.SchemaFor(field.Type)
+			constrain(schema, field.Type, rules)
+			if dive != nil && schema.Items != nil {
+				constrain(schema.Items, field.Type.Elem(), dive)
+			}
+
+			parameter := &Parameter{Name: name, In: in, Required: in == "path", Schema: schema}
+			for _, rule := range rules {
+				if rule.Name == "required" {
+					parameter.Required = true
+				}
+			}
+			parameters = append(parameters, parameter)
+			break
+		}
+	}
+
+	return parameters
+}
+
+// HasBody reports whether t, a request type of web.Handle, has fields that are decoded from the request body.
+func HasBody(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if isParameter(field) || field.Tag.Get("json") == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if HasBody(field.Type) {
+				return true
+			}
+			continue
+		}
+		if field.IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresBody reports whether t, a request type of web.Handle, has body fields validated as required. The body of other
+// request types is optional, a request without one binds the zero value.
+func RequiresBody(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if isParameter(field) || field.Tag.Get("json") == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if RequiresBody(field.Type) {
+				return true
+			}
+			continue
+		}
+		rules, _ := validation.ParseTag(field.Tag.Get("validate"))
+		if field.IsExported() && slices.ContainsFunc(rules, func(rule validation.Rule) bool { return rule.Name == "required" }) {
+			return true
+		}
+	}
+	return false
+}
+
+func isParameter(field reflect.StructField) bool {
+	for _, tag := range parameterSourceTags {
+		if field.Tag.Get(tag) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,21 @@
+package openapi
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+)
+
+//go:embed viewer.html
+var viewerHTML string
+
+var viewer = template.Must(template.New("viewer").Parse(viewerHTML))
+
+// Viewer serves a self-contained page rendering the document found at specURL, relative to the page. It needs no
+// external scripts or stylesheets so it also works offline.
+func Viewer(specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = viewer.Execute(w, struct{ SpecURL string }{SpecURL: specURL})
+	}
+}
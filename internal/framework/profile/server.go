@@ -10,7 +10,9 @@ import (
 	"net/http/pprof"
 )
 
-func ListenAndServe(ctx context.Context, port string) {
+// ListenAndServe serves pprof on the port along with any additional debug routes, such as admin endpoints that must not
+// be exposed on the public port.
+func ListenAndServe(ctx context.Context, port string, routes ...func(mux *http.ServeMux)) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
@@ -20,6 +22,9 @@ func ListenAndServe(ctx context.Context, port string) {
 	mux.Handle("/debug/pprof/block", pprof.Handler("block"))
 	mux.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
 	mux.Handle("/debug/pprof/mutex", pprof.Handler("mutex"))
+	for _, register := range routes {
+		register(mux)
+	}
 
 	profileServer := &http.Server{
 		Addr:    net.JoinHostPort("", port),
@@ -0,0 +1,414 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FieldError is a single rule violation. Field is the JSON path of the value, such as "items[0].name".
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors is every rule violation found in a value.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Field + " " + err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+type rule struct {
+	name  string
+	check func(v reflect.Value) (string, bool)
+}
+
+type fieldRules struct {
+	index  int
+	name   string
+	rules  []rule
+	dive   []rule
+	inline bool
+}
+
+var cache sync.Map // map[reflect.Type][]fieldRules
+
+// Struct validates v against the validate struct tags of its fields, recursing into nested structs. It returns Errors
+// listing every violation or nil. Supported rules are required, min, max, len, oneof, email, regex and dive, which applies
+// the rules after it to each element of a slice or map. The oneof, email and regex rules accept empty values, combine them
+// with required to reject those. The regex rule must be last as its pattern may contain commas.
+//
+//	type Order struct {
+//		Email string   `json:"email" validate:"required,email"`
+//		Items []Item   `json:"items" validate:"min=1,dive"`
+//		Code  string   `json:"code" validate:"len=6,regex=^[A-Z0-9]+$"`
+//	}
+func Struct(v any) error {
+	var errs Errors
+	validate(reflect.ValueOf(v), "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validate(value reflect.Value, path string, errs *Errors) {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		for _, field := range rulesFor(value.Type()) {
+			fieldValue := value.Field(field.index)
+			if field.inline {
+				validate(fieldValue, path, errs)
+				continue
+			}
+
+			fieldPath := join(path, field.name)
+			if !apply(field.rules, fieldValue, fieldPath, errs) {
+				continue
+			}
+
+			if field.dive != nil {
+				dive(field.dive, fieldValue, fieldPath, errs)
+				continue
+			}
+			validate(fieldValue, fieldPath, errs)
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		dive(nil, value, path, errs)
+	}
+}
+
+// apply runs the rules against the value, returning false when the value is missing so that nested checks are skipped.
+func apply(rules []rule, value reflect.Value, path string, errs *Errors) bool {
+	if isNil(value) {
+		if slices.ContainsFunc(rules, func(r rule) bool { return r.name == "required" }) {
+			*errs = append(*errs, FieldError{Field: path, Rule: "required", Message: "is required"})
+		}
+		return false
+	}
+
+	value = indirect(value)
+	for _, r := range rules {
+		if message, ok := r.check(value); !ok {
+			*errs = append(*errs, FieldError{Field: path, Rule: r.name, Message: message})
+		}
+	}
+	return true
+}
+
+func dive(rules []rule, value reflect.Value, path string, errs *Errors) {
+	value = indirect(value)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			if apply(rules, value.Index(i), elemPath, errs) {
+				validate(value.Index(i), elemPath, errs)
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			elemPath := fmt.Sprintf("%s[%v]", path, iter.Key())
+			if apply(rules, iter.Value(), elemPath, errs) {
+				validate(iter.Value(), elemPath, errs)
+			}
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func isNil(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+func indirect(value reflect.Value) reflect.Value {
+	for (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) && !value.IsNil() {
+		value = value.Elem()
+	}
+	return value
+}
+
+func rulesFor(t reflect.Type) []fieldRules {
+	if cached, ok := cache.Load(t); ok {
+		return cached.([]fieldRules)
+	}
+
+	fields, err := parseFields(t)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	cache.Store(t, fields)
+	return fields
+}
+
+func parseFields(t reflect.Type) ([]fieldRules, error) {
+	var fields []fieldRules
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := fieldName(field)
+
+		rules, dive, err := parse(field.Tag.Get("validate"))
+		if err != nil {
+			return nil, fmt.Errorf("validation: invalid tag on %s.%s: %w", t.Name(), field.Name, err)
+		}
+
+		fields = append(fields, fieldRules{
+			index:  i,
+			name:   name,
+			rules:  rules,
+			dive:   dive,
+			inline: field.Anonymous && field.Tag.Get("json") == "" && indirectType(field.Type).Kind() == reflect.Struct,
+		})
+	}
+	return fields, nil
+}
+
+// Check returns an error for the first invalid validate tag of t or of the types nested in it, so that a mistake is
+// found when the type is registered rather than when a value is first validated, where Struct panics on it.
+func Check(t reflect.Type) error {
+	return check(t, map[reflect.Type]bool{})
+}
+
+func check(t reflect.Type, seen map[reflect.Type]bool) error {
+	t = indirectType(t)
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return check(t.Elem(), seen)
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	fields, err := parseFields(t)
+	if err != nil {
+		return err
+	}
+	cache.Store(t, fields)
+
+	for _, field := range fields {
+		if err := check(t.Field(field.index).Type, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldName returns the name a client knows the field by, its JSON name or the parameter it is bound from.
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name != "" && name != "-" {
+		return name
+	}
+
+	for _, tag := range []string{"path", "query", "header"} {
+		if name = field.Tag.Get(tag); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// Rule is a single rule of a validate tag, such as Name "min" and Param "1" for min=1.
+type Rule struct {
+	Name  string
+	Param string
+}
+
+// ParseTag splits a validate tag into the rules for the field and, after dive, the rules for its elements. The dive rules
+// are nil when the tag has no dive.
+func ParseTag(tag string) ([]Rule, []Rule) {
+	var (
+		rules  []Rule
+		dive   []Rule
+		diving bool
+	)
+
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "regex=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+
+		if part == "dive" {
+			diving = true
+			dive = []Rule{}
+			continue
+		}
+
+		name, param, _ := strings.Cut(part, "=")
+		if diving {
+			dive = append(dive, Rule{Name: name, Param: param})
+		} else {
+			rules = append(rules, Rule{Name: name, Param: param})
+		}
+	}
+
+	return rules, dive
+}
+
+// parse compiles a validate tag into the checks for the field and, after dive, for its elements.
+func parse(tag string) ([]rule, []rule, error) {
+	rules, dive := ParseTag(tag)
+
+	compiled, err := compile(rules)
+	if err != nil || dive == nil {
+		return compiled, nil, err
+	}
+
+	compiledDive, err := compile(dive)
+	if compiledDive == nil {
+		compiledDive = []rule{}
+	}
+	return compiled, compiledDive, err
+}
+
+func compile(rules []Rule) ([]rule, error) {
+	var compiled []rule
+	for _, r := range rules {
+		c, err := newRule(r.Name, r.Param)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func newRule(name, param string) (rule, error) {
+	r := rule{name: name}
+
+	switch name {
+	case "required":
+		r.check = func(v reflect.Value) (string, bool) {
+			switch v.Kind() {
+			case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+				return "is required", v.Len() > 0
+			}
+			return "is required", !v.IsZero()
+		}
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return r, fmt.Errorf("%s requires a number: %w", name, err)
+		}
+		r.check = func(v reflect.Value) (string, bool) {
+			size, isLength, ok := measure(v)
+			if !ok {
+				return "", true
+			}
+			return compare(name, param, size, limit, isLength)
+		}
+	case "oneof":
+		options := strings.Fields(param)
+		r.check = func(v reflect.Value) (string, bool) {
+			if v.IsZero() {
+				return "", true
+			}
+			return fmt.Sprintf("must be one of [%s]", strings.Join(options, ", ")), slices.Contains(options, fmt.Sprint(v.Interface()))
+		}
+	case "email":
+		r.check = func(v reflect.Value) (string, bool) {
+			if v.Kind() != reflect.String || v.Len() == 0 {
+				return "", true
+			}
+			address, err := mail.ParseAddress(v.String())
+			return "must be a valid email address", err == nil && address.Address == v.String()
+		}
+	case "regex":
+		pattern, err := regexp.Compile(param)
+		if err != nil {
+			return r, fmt.Errorf("invalid regex: %w", err)
+		}
+		r.check = func(v reflect.Value) (string, bool) {
+			if v.Kind() != reflect.String || v.Len() == 0 {
+				return "", true
+			}
+			return fmt.Sprintf("must match %s", param), pattern.MatchString(v.String())
+		}
+	default:
+		return r, fmt.Errorf("unknown rule %q", name)
+	}
+
+	return r, nil
+}
+
+// measure returns the length of strings and collections or the value of numbers.
+func measure(v reflect.Value) (float64, bool, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true, true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	}
+	return 0, false, false
+}
+
+func compare(name, param string, size, limit float64, isLength bool) (string, bool) {
+	subject := "must be"
+	if isLength {
+		subject = "length must be"
+	}
+
+	switch name {
+	case "min":
+		return fmt.Sprintf("%s at least %s", subject, param), size >= limit
+	case "max":
+		return fmt.Sprintf("%s at most %s", subject, param), size <= limit
+	default:
+		return fmt.Sprintf("%s exactly %s", subject, param), size == limit
+	}
+}
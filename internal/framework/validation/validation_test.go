@@ -0,0 +1,86 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	Zip string `json:"zip" validate:"required,len=5,regex=^[0-9]+$"`
+}
+
+type user struct {
+	Name      string            `json:"name" validate:"required,min=2,max=10"`
+	Email     string            `json:"email" validate:"email"`
+	Age       *int              `json:"age" validate:"required,min=18"`
+	Role      string            `json:"role" validate:"oneof=admin member"`
+	Tags      []string          `json:"tags" validate:"max=2,dive,min=3"`
+	Addresses []address         `json:"addresses" validate:"min=1"`
+	Labels    map[string]string `json:"labels" validate:"dive,oneof=a b"`
+	Tenant    string            `query:"tenant" json:"-" validate:"required"`
+}
+
+func TestStruct(t *testing.T) {
+	age := 17
+	u := user{
+		Name:      "a",
+		Email:     "not-an-email",
+		Age:       &age,
+		Role:      "owner",
+		Tags:      []string{"ok!", "no", "fine"},
+		Addresses: []address{{Zip: "12345"}, {Zip: "12a4"}},
+		Labels:    map[string]string{"x": "c"},
+	}
+
+	err := Struct(&u)
+	require.Error(t, err)
+
+	var fields []string
+	for _, e := range err.(Errors) {
+		fields = append(fields, e.Field+":"+e.Rule)
+	}
+	assert.ElementsMatch(t, []string{
+		"name:min",
+		"email:email",
+		"age:min",
+		"role:oneof",
+		"tags:max",
+		"tags[1]:min",
+		"addresses[1].zip:len",
+		"addresses[1].zip:regex",
+		"labels[x]:oneof",
+		"tenant:required",
+	}, fields)
+}
+
+func TestStruct_Valid(t *testing.T) {
+	age := 30
+	u := user{Name: "ada", Age: &age, Role: "admin", Addresses: []address{{Zip: "12345"}}, Tenant: "acme"}
+	assert.NoError(t, Struct(u))
+}
+
+func TestStruct_RequiredPointer(t *testing.T) {
+	err := Struct(user{Name: "ada", Role: "admin", Addresses: []address{{Zip: "12345"}}, Tenant: "acme"})
+	assert.Equal(t, Errors{{Field: "age", Rule: "required", Message: "is required"}}, err)
+}
+
+func TestCheck(t *testing.T) {
+	type badLimit struct {
+		Name string `validate:"min=two"`
+	}
+	type nested struct {
+		Items []*badLimit `validate:"dive"`
+	}
+	type unknownRule struct {
+		Name string `validate:"requried"`
+	}
+
+	assert.NoError(t, Check(reflect.TypeFor[*user]()))
+	assert.ErrorContains(t, Check(reflect.TypeFor[badLimit]()), "badLimit.Name")
+	assert.ErrorContains(t, Check(reflect.TypeFor[nested]()), "badLimit.Name", "nested types are checked")
+	assert.ErrorContains(t, Check(reflect.TypeFor[map[string]unknownRule]()), `unknown rule "requried"`)
+	assert.NoError(t, Check(reflect.TypeFor[string]()))
+}
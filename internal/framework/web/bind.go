@@ -0,0 +1,181 @@
+package web
+
+import (
+	"bufio"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	durationType        = reflect.TypeFor[time.Duration]()
+)
+
+// bind populates v, a pointer, from the request body decoded by its Content-Type and from the path values, query parameters and headers named by
+// the path, query and header struct tags of its fields. Tagged fields are never set from the body.
+func bind(r *http.Request, v any) error {
+	if hasBody(r) {
+		if err := decodeBody(r, v); err != nil {
+			return err
+		}
+	}
+
+	value := reflect.ValueOf(v).Elem()
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return bindFields(r, value)
+}
+
+// hasBody reports whether the request has a body to decode. A body of unknown length, such as a chunked one, is read
+// ahead so that an empty one counts as none.
+func hasBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return false
+	}
+	if r.ContentLength > 0 {
+		return true
+	}
+
+	body := bufio.NewReader(r.Body)
+	if _, err := body.Peek(1); errors.Is(err, io.EOF) {
+		return false
+	}
+	r.Body = readCloser{Reader: body, Closer: r.Body}
+	return true
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func bindFields(r *http.Request, value reflect.Value) error {
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindFields(r, value.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var (
+			source string
+			name   string
+			values []string
+		)
+		if name = field.Tag.Get("path"); name != "" {
+			source, values = "path value", nonEmpty(r.PathValue(name))
+		} else if name = field.Tag.Get("query"); name != "" {
+			source, values = "query parameter", r.URL.Query()[name]
+		} else if name = field.Tag.Get("header"); name != "" {
+			source, values = "header", r.Header.Values(name)
+		} else {
+			continue
+		}
+
+		// tagged fields only come from their source, so a body cannot set a value meant to come from a header or the path
+		value.Field(i).SetZero()
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setValue(value.Field(i), values); err != nil {
+			return NewValidationError(fmt.Sprintf("invalid %s %q", source, name), err)
+		}
+	}
+
+	return nil
+}
+
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+// setValue parses the raw values into the field, slices receive every value and other kinds the first.
+func setValue(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && !field.Type().Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, raw := range values {
+			if err := setScalar(slice.Index(i), raw); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	return setScalar(field, values[0])
+}
+
+func setScalar(field reflect.Value, raw string) error {
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setScalar(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,388 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+const defaultMediaType = "application/json"
+
+// ErrUnsupportedValue is returned by a codec that cannot encode or decode the given value, for example a protobuf codec
+// given a type that is not a proto.Message. Respond falls back to the next acceptable codec.
+var ErrUnsupportedValue = errors.New("value is not supported by codec")
+
+// Codec encodes and decodes values for one or more media types.
+type Codec interface {
+	MediaTypes() []string
+	Decode(r io.Reader, v any) error
+	Encode(w io.Writer, v any) error
+}
+
+var codecs = struct {
+	sync.RWMutex
+	byType map[string]Codec
+	order  []string
+}{byType: map[string]Codec{}}
+
+func init() {
+	RegisterCodec(jsonCodec{})
+	RegisterCodec(xmlCodec{})
+	RegisterCodec(msgpackCodec{})
+	RegisterCodec(cborCodec{})
+	RegisterCodec(protobufCodec{})
+	RegisterCodec(formCodec{})
+	RegisterCodec(textCodec{})
+}
+
+// RegisterCodec makes the codec available for decoding requests and encoding responses, replacing any codec registered
+// for the same media types. When a client accepts any media type the first registered codec, JSON, is used unless the
+// route prefers another with PreferMediaType.
+func RegisterCodec(codec Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	for _, mediaType := range codec.MediaTypes() {
+		if _, ok := codecs.byType[mediaType]; !ok {
+			codecs.order = append(codecs.order, mediaType)
+		}
+		codecs.byType[mediaType] = codec
+	}
+}
+
+func lookupCodec(mediaType string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	codec, ok := codecs.byType[mediaType]
+	return codec, ok
+}
+
+// decodeBody decodes the request body with the codec selected by its Content-Type, JSON when none is given.
+func decodeBody(r *http.Request, v any) error {
+	defer r.Body.Close()
+
+	mediaType := defaultMediaType
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return &Error{Kind: KindUnsupportedMediaType, Message: "invalid Content-Type", Err: err}
+		}
+		mediaType = parsed
+	}
+
+	codec, ok := lookupCodec(mediaType)
+	if !ok {
+		return &Error{Kind: KindUnsupportedMediaType, Message: fmt.Sprintf("unsupported Content-Type %q", mediaType)}
+	}
+
+	err := codec.Decode(r.Body, v)
+	if err == nil {
+		return nil
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &Error{Kind: KindTooLarge, Message: fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit), Err: err}
+	}
+	if errors.Is(err, ErrUnsupportedValue) {
+		return &Error{Kind: KindUnsupportedMediaType, Message: fmt.Sprintf("Content-Type %q is not supported by this endpoint", mediaType), Err: err}
+	}
+	return NewValidationError("failed to decode request body", err)
+}
+
+// Respond encodes v with the codec that best matches the request's Accept header and writes it with the given status. A
+// request that accepts no registered media type fails with a not acceptable *Error before anything is written.
+func Respond(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		w.WriteHeader(status)
+		return nil
+	}
+
+	preferred, _ := r.Context().Value(preferredMediaTypeKey{}).(string)
+
+	var body bytes.Buffer
+	for _, mediaType := range acceptable(r.Header.Get("Accept"), preferred) {
+		codec, _ := lookupCodec(mediaType)
+
+		body.Reset()
+		if err := codec.Encode(&body, v); err != nil {
+			if errors.Is(err, ErrUnsupportedValue) {
+				continue
+			}
+			return err
+		}
+
+		w.Header().Set("Content-Type", mediaType)
+		w.Header().Add("Vary", "Accept")
+		w.WriteHeader(status)
+		_, _ = w.Write(body.Bytes())
+		return nil
+	}
+
+	return &Error{Kind: KindNotAcceptable, Message: fmt.Sprintf("none of the accepted media types %q can be produced", r.Header.Get("Accept"))}
+}
+
+type preferredMediaTypeKey struct{}
+
+// PreferMediaType creates middleware making Respond encode with the media type, instead of the first registered codec,
+// when the request accepts it as much as any other, for example when it has no Accept header.
+func PreferMediaType(mediaType string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r.WithContext(context.WithValue(r.Context(), preferredMediaTypeKey{}, mediaType)))
+		}
+	}
+}
+
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// acceptable returns the registered media types matching the Accept header ordered by preference. The preferred media
+// type, when registered, goes before the others the header ranks equally.
+func acceptable(accept, preferred string) []string {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	order := slices.Clone(codecs.order)
+	if i := slices.Index(order, preferred); i > 0 {
+		order = slices.Insert(slices.Delete(order, i, i+1), 0, preferred)
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return order
+	}
+
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+	}
+
+	// more specific ranges override the quality of broader ones
+	specificity := func(mediaType string) int {
+		switch {
+		case mediaType == "*/*":
+			return 0
+		case strings.HasSuffix(mediaType, "/*"):
+			return 1
+		}
+		return 2
+	}
+
+	var candidates []acceptRange
+	for _, mediaType := range order {
+		best, quality := -1, 0.0
+		for _, accepted := range ranges {
+			if !matches(accepted.mediaType, mediaType) || specificity(accepted.mediaType) <= best {
+				continue
+			}
+			best, quality = specificity(accepted.mediaType), accepted.quality
+		}
+		if best >= 0 && quality > 0 {
+			candidates = append(candidates, acceptRange{mediaType: mediaType, quality: quality})
+		}
+	}
+
+	// the preferred media type, then registration order, breaks ties between equally preferred media types
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	mediaTypes := make([]string, len(candidates))
+	for i, c := range candidates {
+		mediaTypes[i] = c.mediaType
+	}
+	return mediaTypes
+}
+
+func matches(accepted, mediaType string) bool {
+	if accepted == "*/*" || accepted == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(accepted, "*")
+	return ok && strings.HasPrefix(mediaType, prefix)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) MediaTypes() []string { return []string{"application/json"} }
+
+// Decode strictly decodes a single JSON value, rejecting unknown fields and trailing data.
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.Decode(&json.RawMessage{}) != io.EOF {
+		return errors.New("unexpected data after the request body")
+	}
+	return nil
+}
+
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) MediaTypes() []string { return []string{"application/xml", "text/xml"} }
+
+// Decode rejects elements and attributes that match no field, like the JSON and msgpack codecs reject unknown fields.
+func (xmlCodec) Decode(r io.Reader, v any) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if err := xml.Unmarshal(b, v); err != nil {
+		return err
+	}
+	return checkXMLFields(b, reflect.TypeOf(v))
+}
+
+func (xmlCodec) Encode(w io.Writer, v any) error {
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnsupportedValue, err)
+	}
+	return nil
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}
+}
+
+func (msgpackCodec) Decode(r io.Reader, v any) error {
+	decoder := msgpack.NewDecoder(r)
+	decoder.SetCustomStructTag("json")
+	decoder.DisallowUnknownFields(true)
+	return decoder.Decode(v)
+}
+
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+	return encoder.Encode(v)
+}
+
+type cborCodec struct{}
+
+func (cborCodec) MediaTypes() []string { return []string{"application/cbor"} }
+
+// cborDecMode rejects unknown fields like the JSON and msgpack codecs.
+var cborDecMode = func() cbor.DecMode {
+	mode, err := cbor.DecOptions{ExtraReturnErrors: cbor.ExtraDecErrorUnknownField}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}()
+
+func (cborCodec) Decode(r io.Reader, v any) error {
+	return cborDecMode.NewDecoder(r).Decode(v)
+}
+
+func (cborCodec) Encode(w io.Writer, v any) error {
+	return cbor.NewEncoder(w).Encode(v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) MediaTypes() []string {
+	return []string{"application/x-protobuf", "application/protobuf"}
+}
+
+func (protobufCodec) Decode(r io.Reader, v any) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return ErrUnsupportedValue
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, message)
+}
+
+func (protobufCodec) Encode(w io.Writer, v any) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return ErrUnsupportedValue
+	}
+
+	b, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+type textCodec struct{}
+
+func (textCodec) MediaTypes() []string { return []string{"text/plain"} }
+
+func (textCodec) Decode(r io.Reader, v any) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case *string:
+		*v = string(b)
+	case *[]byte:
+		*v = b
+	default:
+		return ErrUnsupportedValue
+	}
+	return nil
+}
+
+func (textCodec) Encode(w io.Writer, v any) error {
+	switch v := v.(type) {
+	case string:
+		_, err := io.WriteString(w, v)
+		return err
+	case []byte:
+		_, err := w.Write(v)
+		return err
+	case fmt.Stringer:
+		_, err := io.WriteString(w, v.String())
+		return err
+	}
+	return ErrUnsupportedValue
+}
@@ -0,0 +1,137 @@
+package web
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestAcceptable(t *testing.T) {
+	assert.Equal(t, []string{"application/xml", "application/json"}, acceptable("application/json;q=0.5, application/xml", ""))
+	assert.Equal(t, []string{"text/xml", "text/plain"}, acceptable("text/*", ""))
+	assert.Equal(t, "application/json", acceptable("*/*", "")[0])
+	assert.NotContains(t, acceptable("*/*, application/cbor;q=0", ""), "application/cbor")
+	assert.Empty(t, acceptable("image/png", ""))
+
+	assert.Equal(t, "text/plain", acceptable("", "text/plain")[0])
+	assert.Equal(t, "text/plain", acceptable("*/*", "text/plain")[0])
+	assert.Equal(t, []string{"application/json"}, acceptable("application/json", "text/plain"))
+	assert.Equal(t, "application/json", acceptable("application/*, text/plain;q=0.5", "text/plain")[0])
+}
+
+func TestRespond(t *testing.T) {
+	type greeting struct {
+		Message string `json:"message"`
+	}
+
+	tests := map[string]struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		"defaults to json":      {accept: "", status: http.StatusOK, contentType: "application/json"},
+		"negotiates msgpack":    {accept: "application/msgpack", status: http.StatusOK, contentType: "application/msgpack"},
+		"skips unsupported":     {accept: "application/x-protobuf, application/cbor;q=0.5", status: http.StatusOK, contentType: "application/cbor"},
+		"rejects unacceptable":  {accept: "image/png", status: http.StatusNotAcceptable, contentType: problemContentType},
+		"text needs a stringer": {accept: "text/plain", status: http.StatusNotAcceptable, contentType: problemContentType},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+
+			HandlerFuncE(func(w http.ResponseWriter, r *http.Request) error {
+				return Respond(w, r, http.StatusOK, greeting{Message: "hi"})
+			}).ServeHTTP(rec, r)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestRespond_PreferMediaType(t *testing.T) {
+	handler := PreferMediaType("text/plain")(HandlerFuncE(func(w http.ResponseWriter, r *http.Request) error {
+		return Respond(w, r, http.StatusOK, "hi")
+	}).ServeHTTP)
+
+	for accept, want := range map[string]string{"": "hi", "*/*": "hi", "application/json": "\"hi\"\n"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+
+		assert.Equal(t, want, rec.Body.String(), accept)
+	}
+}
+
+func TestDecode_UnknownFields(t *testing.T) {
+	type item struct {
+		XMLName xml.Name `json:"-" xml:"item"`
+		Name    string   `json:"name" xml:"name,attr"`
+		Tags    []string `json:"tags" xml:"tag"`
+	}
+
+	msgpackBody, _ := msgpack.Marshal(map[string]any{"name": "widget", "color": "red"})
+	cborBody, _ := cbor.Marshal(map[string]any{"name": "widget", "color": "red"})
+
+	tests := map[string]struct {
+		contentType string
+		body        string
+	}{
+		"json":          {contentType: "application/json", body: `{"name":"widget","color":"red"}`},
+		"msgpack":       {contentType: "application/msgpack", body: string(msgpackBody)},
+		"cbor":          {contentType: "application/cbor", body: string(cborBody)},
+		"form":          {contentType: "application/x-www-form-urlencoded", body: "name=widget&color=red"},
+		"xml element":   {contentType: "application/xml", body: `<item name="widget"><tag>a</tag><color>red</color></item>`},
+		"xml attribute": {contentType: "application/xml", body: `<item name="widget" color="red"><tag>a</tag></item>`},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			_, err := Decode[item](r)
+			assert.ErrorContains(t, err, "unknown")
+			assert.Equal(t, KindValidation, err.(*Error).Kind)
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`<item xmlns="urn:x" name="widget"><tag>a</tag><tag>b</tag></item>`))
+	r.Header.Set("Content-Type", "application/xml")
+	v, err := Decode[item](r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, v.Tags)
+}
+
+func TestDecode_ContentType(t *testing.T) {
+	type login struct {
+		User string `json:"user" validate:"required"`
+	}
+
+	body, _ := msgpack.Marshal(map[string]string{"user": "ada"})
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/msgpack")
+	v, err := Decode[login](r)
+	assert.NoError(t, err)
+	assert.Equal(t, "ada", v.User)
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("user=grace"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	v, err = Decode[login](r)
+	assert.NoError(t, err)
+	assert.Equal(t, "grace", v.User)
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("user"))
+	r.Header.Set("Content-Type", "application/yaml")
+	_, err = Decode[login](r)
+	assert.Equal(t, KindUnsupportedMediaType, err.(*Error).Kind)
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/taylorono/go-webservice/internal/framework/metrics"
+)
+
+const (
+	_inFlightGauge      = "app_requests_in_flight"
+	_shutdownPhaseGauge = "app_shutdown_phase_duration"
+)
+
+var errDraining = errors.New("server is shutting down")
+
+// inFlight counts the requests currently being served.
+type inFlight struct {
+	count    atomic.Int64
+	registry metrics.Registry
+}
+
+func (f *inFlight) register(registry metrics.Registry) {
+	f.registry = registry
+	registry.RegisterGauge(_inFlightGauge, "Number of requests currently being served")
+	registry.RegisterGauge(_shutdownPhaseGauge, "Duration of each graceful shutdown phase in milliseconds", "phase")
+}
+
+func (f *inFlight) add(delta int64) {
+	n := f.count.Add(delta)
+	if f.registry != nil {
+		f.registry.SetGauge(_inFlightGauge, float64(n))
+	}
+}
+
+// track wraps the handler so every request is counted while it is being served.
+func (f *inFlight) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.add(1)
+		defer f.add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// wait blocks until no requests are in flight or the context is done.
+func (f *inFlight) wait(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for f.count.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// readiness fails the readiness probe once the server has started draining.
+func (s *Server) readiness(_ context.Context) error {
+	if s.draining.Load() {
+		return errDraining
+	}
+	return nil
+}
+
+// drain gracefully stops the http server. Readiness is failed first so the load balancer stops routing new traffic, after
+// the pre-stop delay the listeners are closed and in-flight requests are given until the shutdown timeout to complete
+// before any remaining connections are forcibly closed.
+func (s *Server) drain(httpServer *http.Server) error {
+	s.phase("readiness", func() error {
+		s.draining.Store(true)
+		return nil
+	})
+
+	s.phase("pre-stop", func() error {
+		time.Sleep(s.shutdownDelay)
+		return nil
+	})
+
+	// the parent context has already been canceled so the timeout must start from a fresh context
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	err := s.phase("shutdown", func() error {
+		return httpServer.Shutdown(shutdownCtx)
+	})
+	if err == nil {
+		err = s.phase("in-flight", func() error {
+			return s.inFlight.wait(shutdownCtx)
+		})
+	}
+	if err == nil {
+		return nil
+	}
+
+	slog.Warn("graceful shutdown timed out", slog.Int64("in_flight", s.inFlight.count.Load()), slog.String("error", err.Error()))
+	return s.phase("force-close", httpServer.Close)
+}
+
+// phase runs a single shutdown phase logging and recording its duration.
+func (s *Server) phase(name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	duration := time.Since(start)
+
+	slog.Info("shutdown phase complete", slog.String("phase", name), slog.Duration("duration", duration))
+	if s.metrics != nil {
+		s.metrics.SetGauge(_shutdownPhaseGauge, metrics.ToMilliseconds(duration), name)
+	}
+
+	return err
+}
@@ -0,0 +1,157 @@
+package web
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// records collects the records written to the default logger while a test runs.
+type records struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func captureRecords(t *testing.T) *records {
+	t.Helper()
+
+	r := &records{}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(r))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return r
+}
+
+func (r *records) Enabled(context.Context, slog.Level) bool { return true }
+func (r *records) WithAttrs([]slog.Attr) slog.Handler       { return r }
+func (r *records) WithGroup(string) slog.Handler            { return r }
+
+func (r *records) Handle(_ context.Context, record slog.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, record.Clone())
+	return nil
+}
+
+// attr returns the value of the attribute key of every record with the message.
+func (r *records) attr(msg, key string) []any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var values []any
+	for _, record := range r.records {
+		if record.Message != msg {
+			continue
+		}
+		record.Attrs(func(a slog.Attr) bool {
+			if a.Key == key {
+				values = append(values, a.Value.Any())
+			}
+			return true
+		})
+	}
+	return values
+}
+
+// blockingServer starts the server with a GET /slow route that blocks until release is closed, and requests it.
+func blockingServer(t *testing.T, s *Server) (ts *httptest.Server, release chan struct{}, response chan error) {
+	t.Helper()
+
+	started, release, response := make(chan struct{}), make(chan struct{}), make(chan error, 1)
+	s.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "done")
+	})
+
+	ts = httptest.NewServer(s.inFlight.track(s.mux))
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	go func() {
+		res, err := ts.Client().Get(ts.URL + "/slow")
+		if err == nil {
+			body, _ := io.ReadAll(res.Body)
+			_ = res.Body.Close()
+			if string(body) != "done" {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+		response <- err
+	}()
+	<-started
+	return ts, release, response
+}
+
+// probe returns the status code of the readiness probe.
+func probe(s *Server) int {
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return rec.Code
+}
+
+func TestServer_Drain(t *testing.T) {
+	logs := captureRecords(t)
+	s := NewServer(WithShutdownDelay(50*time.Millisecond), WithShutdownTimeout(time.Second))
+	ts, release, response := blockingServer(t, s)
+
+	assert.Equal(t, int64(1), s.inFlight.count.Load())
+	assert.Equal(t, http.StatusOK, probe(s))
+
+	start := time.Now()
+	drained := make(chan error, 1)
+	go func() { drained <- s.drain(ts.Config) }()
+
+	require.Eventually(t, s.draining.Load, time.Second, time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, probe(s), "readiness fails as soon as draining starts")
+	close(release)
+
+	require.NoError(t, <-drained)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond, "listeners stay open for the shutdown delay")
+	assert.NoError(t, <-response, "the in-flight request completes")
+	assert.Equal(t, int64(0), s.inFlight.count.Load())
+	assert.Equal(t, []any{"readiness", "pre-stop", "shutdown", "in-flight"}, logs.attr("shutdown phase complete", "phase"))
+}
+
+func TestServer_DrainTimeout(t *testing.T) {
+	logs := captureRecords(t)
+	s := NewServer(WithShutdownDelay(0), WithShutdownTimeout(50*time.Millisecond))
+	ts, _, response := blockingServer(t, s)
+
+	start := time.Now()
+	require.NoError(t, s.drain(ts.Config))
+	assert.Less(t, time.Since(start), time.Second, "connections are closed once the timeout elapses")
+	assert.Error(t, <-response, "the in-flight request is cut off")
+
+	assert.Equal(t, []any{int64(1)}, logs.attr("graceful shutdown timed out", "in_flight"))
+	assert.Contains(t, logs.attr("shutdown phase complete", "phase"), "force-close")
+}
+
+func TestInFlight_Wait(t *testing.T) {
+	var f inFlight
+	f.add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, f.wait(ctx), context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		f.add(-1)
+	}()
+	assert.NoError(t, f.wait(context.Background()))
+}
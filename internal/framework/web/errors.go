@@ -0,0 +1,171 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/taylorono/go-webservice/internal/framework/metrics"
+	"github.com/taylorono/go-webservice/internal/framework/validation"
+)
+
+const problemContentType = "application/problem+json"
+
+// ErrorKind classifies an error returned by a handler and determines its response status.
+type ErrorKind string
+
+const (
+	KindValidation   ErrorKind = "validation"
+	KindNotFound     ErrorKind = "not_found"
+	KindConflict     ErrorKind = "conflict"
+	KindUnauthorized ErrorKind = "unauthorized"
+	KindUpstream     ErrorKind = "upstream"
+	KindTooLarge     ErrorKind = "too_large"
+	KindInternal     ErrorKind = "internal"
+
+	KindNotAcceptable        ErrorKind = "not_acceptable"
+	KindUnsupportedMediaType ErrorKind = "unsupported_media_type"
+)
+
+var kindStatus = map[ErrorKind]int{
+	KindValidation:   http.StatusBadRequest,
+	KindNotFound:     http.StatusNotFound,
+	KindConflict:     http.StatusConflict,
+	KindUnauthorized: http.StatusUnauthorized,
+	KindUpstream:     http.StatusBadGateway,
+	KindTooLarge:     http.StatusRequestEntityTooLarge,
+	KindInternal:     http.StatusInternalServerError,
+
+	KindNotAcceptable:        http.StatusNotAcceptable,
+	KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+}
+
+// Error is an error with a kind and a message that is safe to show to clients. The wrapped error is only exposed when
+// error details are enabled.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Message, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the http status code for the error kind.
+func (e *Error) Status() int {
+	if status, ok := kindStatus[e.Kind]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+func NewValidationError(message string, err error) *Error {
+	return &Error{Kind: KindValidation, Message: message, Err: err}
+}
+
+func NewNotFoundError(message string, err error) *Error {
+	return &Error{Kind: KindNotFound, Message: message, Err: err}
+}
+
+func NewConflictError(message string, err error) *Error {
+	return &Error{Kind: KindConflict, Message: message, Err: err}
+}
+
+func NewUnauthorizedError(message string, err error) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message, Err: err}
+}
+
+func NewUpstreamError(message string, err error) *Error {
+	return &Error{Kind: KindUpstream, Message: message, Err: err}
+}
+
+// Problem is an RFC 7807 problem details response body.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Kind      ErrorKind         `json:"kind"`
+	Errors    validation.Errors `json:"errors,omitempty"`
+	Debug     string            `json:"debug,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
+}
+
+// HandlerFuncE is an http handler that returns an error. A returned error is written as an RFC 7807 problem, so the
+// handler must not have written a response when it returns one.
+type HandlerFuncE func(w http.ResponseWriter, r *http.Request) error
+
+func (h HandlerFuncE) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+type errorDetailsKey struct{}
+
+// withErrorDetails marks requests so that internal error details are included in problem responses.
+func withErrorDetails(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), errorDetailsKey{}, true)))
+	})
+}
+
+// WriteError writes the error as an application/problem+json response, logging it and labelling the request metrics with
+// its kind. Errors that are not an *Error are treated as internal and their message is hidden from the client.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var webErr *Error
+	if !errors.As(err, &webErr) {
+		webErr = &Error{Kind: KindInternal, Message: "an internal error occurred", Err: err}
+	}
+
+	problem := Problem{
+		Type:      "about:blank",
+		Status:    webErr.Status(),
+		Title:     http.StatusText(webErr.Status()),
+		Detail:    webErr.Message,
+		Instance:  r.URL.Path,
+		Kind:      webErr.Kind,
+		RequestID: requestID(r),
+	}
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		problem.Errors = fieldErrs
+	}
+	if details, _ := r.Context().Value(errorDetailsKey{}).(bool); details && webErr.Err != nil {
+		problem.Debug = webErr.Err.Error()
+	}
+
+	level := slog.LevelInfo
+	if problem.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	slog.Log(r.Context(), level, "request failed",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("kind", string(webErr.Kind)),
+		slog.Int("status", problem.Status),
+		slog.String("error", err.Error()),
+	)
+	metrics.SetErrorKind(r.Context(), string(webErr.Kind))
+
+	writeProblem(w, problem)
+}
+
+func writeProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,131 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylorono/go-webservice/internal/framework/metrics"
+	"github.com/taylorono/go-webservice/internal/framework/validation"
+)
+
+// scrape returns the metrics served by the reporter.
+func scrape(t *testing.T, reporter *metrics.PrometheusReporter) string {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	reporter.Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestError_Status(t *testing.T) {
+	tests := map[ErrorKind]int{
+		KindValidation:           http.StatusBadRequest,
+		KindNotFound:             http.StatusNotFound,
+		KindConflict:             http.StatusConflict,
+		KindUnauthorized:         http.StatusUnauthorized,
+		KindUpstream:             http.StatusBadGateway,
+		KindTooLarge:             http.StatusRequestEntityTooLarge,
+		KindInternal:             http.StatusInternalServerError,
+		KindNotAcceptable:        http.StatusNotAcceptable,
+		KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+		ErrorKind("unknown"):     http.StatusInternalServerError,
+	}
+
+	for kind, status := range tests {
+		t.Run(string(kind), func(t *testing.T) {
+			assert.Equal(t, status, (&Error{Kind: kind}).Status())
+		})
+	}
+}
+
+func TestError_Unwrap(t *testing.T) {
+	cause := errors.New("duplicate key")
+	err := NewConflictError("item already exists", cause)
+
+	assert.Equal(t, "item already exists: duplicate key", err.Error())
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "item not found", NewNotFoundError("item not found", nil).Error())
+}
+
+func TestWriteError(t *testing.T) {
+	fieldErrs := validation.Errors{{Field: "name", Rule: "required", Message: "is required"}}
+
+	tests := map[string]struct {
+		err     error
+		details bool
+		status  int
+		kind    ErrorKind
+		detail  string
+		debug   string
+		errors  int
+	}{
+		"kind decides the status": {
+			err: NewUnauthorizedError("missing token", nil), status: http.StatusUnauthorized, kind: KindUnauthorized,
+			detail: "missing token",
+		},
+		"wrapped errors keep their kind": {
+			err:    fmt.Errorf("loading item: %w", NewNotFoundError("item not found", nil)),
+			status: http.StatusNotFound, kind: KindNotFound, detail: "item not found",
+		},
+		"field errors are listed": {
+			err:    NewValidationError("invalid item", fieldErrs),
+			status: http.StatusBadRequest, kind: KindValidation, detail: "invalid item", errors: 1,
+		},
+		"cause is hidden by default": {
+			err:    NewUpstreamError("inventory unavailable", errors.New("dial tcp: connection refused")),
+			status: http.StatusBadGateway, kind: KindUpstream, detail: "inventory unavailable",
+		},
+		"cause is shown with error details": {
+			err: NewUpstreamError("inventory unavailable", errors.New("dial tcp: connection refused")), details: true,
+			status: http.StatusBadGateway, kind: KindUpstream, detail: "inventory unavailable",
+			debug: "dial tcp: connection refused",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			reporter := metrics.NewPrometheusReporter()
+			s := NewServer(WithErrorDetails(tt.details), WithMetricRegistry(reporter))
+			s.HandleFunc("GET /items/{id}", HandlerFuncE(func(w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			}).ServeHTTP)
+
+			req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+			req.Header.Set("X-Request-ID", "abc")
+			rec := httptest.NewRecorder()
+			s.handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, problemContentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+
+			var problem Problem
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+			assert.Equal(t, Problem{
+				Type:      "about:blank",
+				Title:     http.StatusText(tt.status),
+				Status:    tt.status,
+				Detail:    tt.detail,
+				Instance:  "/items/1",
+				Kind:      tt.kind,
+				Errors:    problem.Errors,
+				Debug:     tt.debug,
+				RequestID: "abc",
+			}, problem)
+			assert.Len(t, problem.Errors, tt.errors)
+
+			assert.Contains(t, scrape(t, reporter),
+				`app_request_errors_total{kind="`+string(tt.kind)+`",method="GET",path="/items/{id}"} 1`,
+				"the request metrics are labelled with the kind")
+		})
+	}
+}
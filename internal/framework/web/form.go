@@ -0,0 +1,151 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// formCodec decodes application/x-www-form-urlencoded bodies into structs using the form struct tag of each field,
+// falling back to its JSON name. Keys matching no field are rejected, like unknown fields by the JSON codec.
+type formCodec struct{}
+
+func (formCodec) MediaTypes() []string { return []string{"application/x-www-form-urlencoded"} }
+
+func (formCodec) Decode(r io.Reader, v any) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case *url.Values:
+		*v = values
+		return nil
+	case *map[string][]string:
+		*v = values
+		return nil
+	}
+
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return ErrUnsupportedValue
+	}
+
+	known := map[string]bool{}
+	if err := decodeForm(values, value.Elem(), known); err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if !known[name] {
+			return fmt.Errorf("unknown form field %q", name)
+		}
+	}
+	return nil
+}
+
+// decodeForm sets the fields of value from the values, adding the name of every field to known.
+func decodeForm(values url.Values, value reflect.Value, known map[string]bool) error {
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := decodeForm(values, value.Field(i), known); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := formName(field)
+		if name == "" {
+			continue
+		}
+		known[name] = true
+		if len(values[name]) == 0 {
+			continue
+		}
+
+		if err := setValue(value.Field(i), values[name]); err != nil {
+			return fmt.Errorf("invalid form field %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (formCodec) Encode(w io.Writer, v any) error {
+	values := url.Values{}
+
+	switch v := v.(type) {
+	case url.Values:
+		values = v
+	case map[string][]string:
+		values = v
+	case map[string]string:
+		for k, s := range v {
+			values.Set(k, s)
+		}
+	default:
+		value := reflect.Indirect(reflect.ValueOf(v))
+		if value.Kind() != reflect.Struct {
+			return ErrUnsupportedValue
+		}
+		encodeForm(values, value)
+	}
+
+	_, err := io.WriteString(w, values.Encode())
+	return err
+}
+
+func encodeForm(values url.Values, value reflect.Value) {
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldValue := reflect.Indirect(value.Field(i))
+		if field.Anonymous && fieldValue.Kind() == reflect.Struct {
+			encodeForm(values, fieldValue)
+			continue
+		}
+
+		name := formName(field)
+		if name == "" || !fieldValue.IsValid() {
+			continue
+		}
+
+		if fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() != reflect.Uint8 {
+			for j := range fieldValue.Len() {
+				values.Add(name, fmt.Sprint(fieldValue.Index(j).Interface()))
+			}
+			continue
+		}
+		values.Set(name, fmt.Sprint(fieldValue.Interface()))
+	}
+}
+
+// formName returns the form field name of a struct field, or empty when the field is excluded.
+func formName(field reflect.StructField) string {
+	for _, tag := range []string{"form", "json"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type namedMiddleware struct {
+	name       string
+	middleware Middleware
+}
+
+// Group registers routes under a common path prefix. Routes in a group are wrapped by the server's middleware, then the
+// middleware of every enclosing group, then the group's own middleware, so the first middleware registered is always
+// the outermost.
+type Group struct {
+	server     *Server
+	parent     *Group
+	prefix     string
+	middleware []Middleware
+	without    []string
+}
+
+// Group creates a route group under prefix with additional middleware.
+func (s *Server) Group(prefix string, middleware ...Middleware) *Group {
+	return &Group{server: s, prefix: prefix, middleware: middleware}
+}
+
+// Without returns a group whose routes skip the named server middleware, for example "metrics".
+func (s *Server) Without(names ...string) *Group {
+	return &Group{server: s, without: names}
+}
+
+// Group creates a nested route group under prefix with additional middleware.
+func (g *Group) Group(prefix string, middleware ...Middleware) *Group {
+	return &Group{server: g.server, parent: g, prefix: prefix, middleware: middleware}
+}
+
+// Without returns a group with the same prefix whose routes skip the named server middleware.
+func (g *Group) Without(names ...string) *Group {
+	return &Group{server: g.server, parent: g, without: names}
+}
+
+// HandleFunc registers a new route under the group prefix applying the server and group middleware.
+func (g *Group) HandleFunc(pattern string, handler http.HandlerFunc) {
+	g.handleRoute(pattern, handler, nil)
+}
+
+func (g *Group) handleRoute(pattern string, handler http.HandlerFunc, doc *routeDoc) {
+	var (
+		prefix          string
+		without         []string
+		groupMiddleware []Middleware
+	)
+
+	// collect the prefix and middleware of the enclosing groups from the innermost outwards
+	for group := g; group != nil; group = group.parent {
+		prefix = strings.TrimSuffix(group.prefix, "/") + prefix
+		without = append(without, group.without...)
+		groupMiddleware = append(slices.Clone(group.middleware), groupMiddleware...)
+	}
+
+	// server middleware is always the outermost
+	var middleware []Middleware
+	for _, m := range g.server.middleware {
+		if m.name == "" || !slices.Contains(without, m.name) {
+			middleware = append(middleware, m.middleware)
+		}
+	}
+	middleware = append(middleware, groupMiddleware...)
+
+	g.server.handle(joinPattern(prefix, pattern), handler, middleware, doc)
+}
+
+// joinPattern inserts the prefix in front of the path of a "[METHOD ][HOST]/PATH" pattern.
+func joinPattern(prefix, pattern string) string {
+	if prefix == "" {
+		return pattern
+	}
+
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		method, path = "", pattern
+	}
+
+	host, path, _ := strings.Cut(path, "/")
+	path = host + prefix + "/" + path
+
+	if method == "" {
+		return path
+	}
+	return method + " " + path
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tag(name string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Chain", name)
+			next(w, r)
+		}
+	}
+}
+
+func TestGroup_HandleFunc(t *testing.T) {
+	s := NewServer(WithMiddleware(tag("server")), WithNamedMiddleware("auth", tag("auth")))
+
+	api := s.Group("/api", tag("api"))
+	v1 := api.Group("/v1/", tag("v1"))
+	v1.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	v1.Without("auth").HandleFunc("GET /internal", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := map[string]struct {
+		path  string
+		chain string
+	}{
+		"nested group applies middleware outermost first": {path: "/api/v1/users/1", chain: "server,auth,api,v1"},
+		"without skips named server middleware":           {path: "/api/v1/internal", chain: "server,api,v1"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.chain, strings.Join(rec.Header().Values("X-Chain"), ","))
+		})
+	}
+}
+
+func TestJoinPattern(t *testing.T) {
+	assert.Equal(t, "GET /api/items", joinPattern("/api", "GET /items"))
+	assert.Equal(t, "/api/", joinPattern("/api", "/"))
+	assert.Equal(t, "POST example.com/api/items", joinPattern("/api", "POST example.com/items"))
+	assert.Equal(t, "GET /items", joinPattern("", "GET /items"))
+}
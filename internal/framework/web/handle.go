@@ -0,0 +1,115 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/taylorono/go-webservice/internal/framework/validation"
+)
+
+// Mux registers http handlers, it is satisfied by Server and Group.
+type Mux interface {
+	HandleFunc(pattern string, handler http.HandlerFunc)
+}
+
+// Validator is implemented by request types that check themselves once they have been decoded.
+type Validator interface {
+	Validate() error
+}
+
+// StatusCoder is implemented by response types that choose their own response status.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+type handleConfig struct {
+	status  int
+	summary string
+}
+
+type HandleOption func(*handleConfig)
+
+// WithStatus sets the status written for a successful response, by default 200 OK.
+func WithStatus(status int) HandleOption {
+	return func(c *handleConfig) {
+		c.status = status
+	}
+}
+
+// WithSummary sets the summary of the operation in the OpenAPI document.
+func WithSummary(summary string) HandleOption {
+	return func(c *handleConfig) {
+		c.summary = summary
+	}
+}
+
+// Handle registers a typed handler on the mux. The request is bound from the path values, query parameters and headers
+// named by the path, query and header struct tags of Req and from the body decoded by its Content-Type, then validated
+// against its validate struct tags and by Validate when Req implements Validator. The response of fn is encoded by Respond
+// with the status from WithStatus, or from the response itself when it implements StatusCoder. Errors from any step are
+// written as problems. It panics when Req has an invalid validate tag. When mux is a Server or Group the route is
+// described in the OpenAPI document by Req and Resp.
+func Handle[Req, Resp any](mux Mux, pattern string, fn func(context.Context, Req) (Resp, error), opts ...HandleOption) {
+	config := handleConfig{status: http.StatusOK}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	// fail at registration like the mux does for an invalid pattern, rather than on the first request
+	if err := validation.Check(reflect.TypeFor[Req]()); err != nil {
+		panic(fmt.Sprintf("web: cannot register %s: %s", pattern, err))
+	}
+
+	handler := HandlerFuncE(func(w http.ResponseWriter, r *http.Request) error {
+		var req Req
+		if err := bind(r, &req); err != nil {
+			return err
+		}
+
+		if err := validation.Struct(&req); err != nil {
+			return NewValidationError("request validation failed", err)
+		}
+
+		if validator, ok := any(&req).(Validator); ok {
+			if err := validator.Validate(); err != nil {
+				return asValidationError(err)
+			}
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			return err
+		}
+
+		status := config.status
+		if coder, ok := any(resp).(StatusCoder); ok {
+			status = coder.StatusCode()
+		}
+
+		return Respond(w, r, status, resp)
+	})
+
+	if router, ok := mux.(router); ok {
+		router.handleRoute(pattern, handler.ServeHTTP, &routeDoc{
+			request:  reflect.TypeFor[Req](),
+			response: reflect.TypeFor[Resp](),
+			status:   config.status,
+			summary:  config.summary,
+		})
+		return
+	}
+
+	mux.HandleFunc(pattern, handler.ServeHTTP)
+}
+
+// asValidationError keeps errors that already have a kind and treats any other as a validation failure.
+func asValidationError(err error) error {
+	var webErr *Error
+	if errors.As(err, &webErr) {
+		return err
+	}
+	return NewValidationError(err.Error(), err)
+}
@@ -0,0 +1,195 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type createItem struct {
+	Store  string   `path:"store" json:"-"`
+	Tags   []string `query:"tag" json:"-"`
+	Limit  *int     `query:"limit" json:"-"`
+	Tenant string   `header:"X-Tenant" json:"-"`
+	Name   string   `json:"name" validate:"required,max=8"`
+}
+
+type item struct {
+	Store  string   `json:"store"`
+	Tags   []string `json:"tags"`
+	Limit  int      `json:"limit"`
+	Tenant string   `json:"tenant"`
+	Name   string   `json:"name"`
+}
+
+func TestHandle(t *testing.T) {
+	s := NewServer()
+	Handle(s, "POST /stores/{store}/items", func(_ context.Context, req createItem) (item, error) {
+		if req.Name == "taken" {
+			return item{}, NewConflictError("item already exists", nil)
+		}
+		return item{Store: req.Store, Tags: req.Tags, Limit: *req.Limit, Tenant: req.Tenant, Name: req.Name}, nil
+	}, WithStatus(http.StatusCreated))
+
+	tests := map[string]struct {
+		target string
+		body   string
+		status int
+		want   string
+		errors int
+	}{
+		"binds path, query, header and body": {
+			target: "/stores/s1/items?tag=a&tag=b&limit=5",
+			body:   `{"name":"widget"}`,
+			status: http.StatusCreated,
+			want:   `{"store":"s1","tags":["a","b"],"limit":5,"tenant":"acme","name":"widget"}`,
+		},
+		"invalid query parameter": {
+			target: "/stores/s1/items?limit=five",
+			body:   `{"name":"widget"}`,
+			status: http.StatusBadRequest,
+		},
+		"invalid body": {
+			target: "/stores/s1/items?limit=5",
+			body:   `{"name":`,
+			status: http.StatusBadRequest,
+		},
+		"unknown field": {
+			target: "/stores/s1/items?limit=5",
+			body:   `{"name":"widget","color":"red"}`,
+			status: http.StatusBadRequest,
+		},
+		"trailing data": {
+			target: "/stores/s1/items?limit=5",
+			body:   `{"name":"widget"} {}`,
+			status: http.StatusBadRequest,
+		},
+		"field validation": {
+			target: "/stores/s1/items?limit=5",
+			body:   `{"name":"a very long name"}`,
+			status: http.StatusBadRequest,
+			errors: 1,
+		},
+		"business error": {
+			target: "/stores/s1/items?limit=5",
+			body:   `{"name":"taken"}`,
+			status: http.StatusConflict,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("X-Tenant", "acme")
+			rec := httptest.NewRecorder()
+			s.mux.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.want != "" {
+				assert.JSONEq(t, tt.want, rec.Body.String())
+				return
+			}
+
+			var problem Problem
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+			assert.Equal(t, problemContentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Len(t, problem.Errors, tt.errors)
+		})
+	}
+}
+
+func TestHandle_TaggedFieldsIgnoreBody(t *testing.T) {
+	type request struct {
+		UserID string `header:"X-User-ID"`
+		ID     string `path:"id"`
+		Name   string `json:"name" form:"name"`
+	}
+
+	s := NewServer()
+	Handle(s, "POST /users/{id}", func(_ context.Context, req request) (request, error) {
+		return req, nil
+	})
+
+	tests := map[string]struct {
+		contentType string
+		body        string
+	}{
+		"json": {contentType: "application/json", body: `{"UserID":"admin","ID":"2","name":"widget"}`},
+		"form": {contentType: "application/x-www-form-urlencoded", body: "UserID=admin&ID=2&name=widget"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users/1", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			s.mux.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.JSONEq(t, `{"UserID":"","ID":"1","name":"widget"}`, rec.Body.String(),
+				"fields bound from headers and the path cannot be spoofed by the body")
+		})
+	}
+}
+
+func TestHandle_InvalidValidateTag(t *testing.T) {
+	type request struct {
+		Name string `json:"name" validate:"max=ten"`
+	}
+
+	assert.PanicsWithValue(t, `web: cannot register POST /items: validation: invalid tag on request.Name: max requires a number: strconv.ParseFloat: parsing "ten": invalid syntax`, func() {
+		Handle(NewServer(), "POST /items", func(_ context.Context, req request) (request, error) { return req, nil })
+	})
+}
+
+func TestHandle_ChunkedBody(t *testing.T) {
+	type request struct {
+		Limit int    `query:"limit"`
+		Name  string `json:"name"`
+	}
+
+	s := NewServer()
+	Handle(s, "POST /items", func(_ context.Context, req request) (request, error) { return req, nil })
+
+	tests := map[string]struct {
+		body string
+		want string
+	}{
+		"empty body is no body": {body: "", want: `{"Limit":5,"name":""}`},
+		"body is decoded":       {body: `{"name":"widget"}`, want: `{"Limit":5,"name":"widget"}`},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/items?limit=5", io.NopCloser(strings.NewReader(tt.body)))
+			req.ContentLength = -1
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			s.mux.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.JSONEq(t, tt.want, rec.Body.String())
+		})
+	}
+}
+
+func TestWriteError_HidesInternalErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("connection refused"))
+
+	var problem Problem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+	assert.Equal(t, http.StatusInternalServerError, problem.Status)
+	assert.Equal(t, KindInternal, problem.Kind)
+	assert.NotContains(t, problem.Detail, "connection refused")
+	assert.Empty(t, problem.Debug)
+}
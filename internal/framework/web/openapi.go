@@ -0,0 +1,130 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/taylorono/go-webservice/internal/framework/openapi"
+)
+
+// routeDoc describes the request and response types of a route registered by Handle.
+type routeDoc struct {
+	request  reflect.Type
+	response reflect.Type
+	status   int
+	summary  string
+}
+
+type route struct {
+	pattern string
+	doc     *routeDoc
+}
+
+// router is implemented by Server and Group so that Handle can register a route together with its description.
+type router interface {
+	handleRoute(pattern string, handler http.HandlerFunc, doc *routeDoc)
+}
+
+var problemType = reflect.TypeFor[Problem]()
+
+func defaultOpenAPIInfo() openapi.Info {
+	info := openapi.Info{Title: "API", Version: "dev"}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if build.Main.Path != "" {
+			info.Title = path.Base(build.Main.Path)
+		}
+		if build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+	}
+	return info
+}
+
+// OpenAPI builds an OpenAPI 3.1 document describing the routes registered so far. Routes registered by Handle are
+// described by their request and response types, other routes by their pattern alone. Patterns without a method are left
+// out as they match every method.
+func (s *Server) OpenAPI() *openapi.Document {
+	document := openapi.NewDocument(s.openAPIInfo.Title, s.openAPIInfo.Version)
+
+	s.routesMu.Lock()
+	routes := slices.Clone(s.routes)
+	s.routesMu.Unlock()
+
+	for _, route := range routes {
+		method, pattern, found := strings.Cut(route.pattern, " ")
+		if !found {
+			continue
+		}
+
+		// the host of a pattern has no place in a path item
+		_, pattern, _ = strings.Cut(strings.TrimSpace(pattern), "/")
+		apiPath := openapi.Path("/" + pattern)
+
+		document.AddOperation(method, apiPath, operation(document, method, apiPath, route.doc))
+	}
+
+	return document
+}
+
+func operation(document *openapi.Document, method, apiPath string, doc *routeDoc) *openapi.Operation {
+	problem := &openapi.Response{
+		Description: "Error",
+		Content:     map[string]*openapi.MediaType{problemContentType: {Schema: document.SchemaFor(problemType)}},
+	}
+
+	op := &openapi.Operation{
+		OperationID: openapi.OperationID(method, apiPath),
+		Responses:   map[string]*openapi.Response{"default": problem},
+	}
+
+	if doc == nil {
+		op.Responses[strconv.Itoa(http.StatusOK)] = &openapi.Response{Description: http.StatusText(http.StatusOK)}
+		op.Parameters = pathParameters(apiPath, nil)
+		return op
+	}
+
+	op.Summary = doc.summary
+	op.Parameters = pathParameters(apiPath, document.Parameters(doc.request))
+
+	if openapi.HasBody(doc.request) {
+		op.RequestBody = &openapi.RequestBody{
+			Required: openapi.RequiresBody(doc.request),
+			Content:  map[string]*openapi.MediaType{defaultMediaType: {Schema: document.SchemaFor(doc.request)}},
+		}
+	}
+
+	success := &openapi.Response{Description: http.StatusText(doc.status)}
+	if doc.status != http.StatusNoContent && doc.status != http.StatusNotModified {
+		success.Content = map[string]*openapi.MediaType{defaultMediaType: {Schema: document.SchemaFor(doc.response)}}
+	}
+	op.Responses[strconv.Itoa(doc.status)] = success
+
+	if len(op.Parameters) > 0 || op.RequestBody != nil {
+		op.Responses[strconv.Itoa(http.StatusBadRequest)] = problem
+	}
+
+	return op
+}
+
+// pathParameters adds the wildcards of the path that the request type does not bind as string path parameters.
+func pathParameters(apiPath string, parameters []*openapi.Parameter) []*openapi.Parameter {
+	for _, name := range openapi.PathParameters(apiPath) {
+		if !slices.ContainsFunc(parameters, func(p *openapi.Parameter) bool { return p.In == "path" && p.Name == name }) {
+			parameters = append(parameters, &openapi.Parameter{Name: name, In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}})
+		}
+	}
+	return parameters
+}
+
+func (s *Server) openAPIHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(s.OpenAPI())
+}
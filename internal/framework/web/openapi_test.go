@@ -0,0 +1,74 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylorono/go-webservice/internal/framework/openapi"
+)
+
+func TestServer_OpenAPI(t *testing.T) {
+	s := NewServer(WithOpenAPIInfo("stores", "1.2.3"))
+	Handle(s.Group("/v1"), "POST /stores/{store}/items", func(context.Context, createItem) (item, error) {
+		return item{}, nil
+	}, WithStatus(http.StatusCreated), WithSummary("Create an item"))
+	Handle(s.Group(""), "PATCH /stores/{store}", func(context.Context, renameStore) (item, error) {
+		return item{}, nil
+	})
+	s.HandleFunc("DELETE /stores/{store}", func(w http.ResponseWriter, r *http.Request) {})
+	s.HandleFunc("/any", func(w http.ResponseWriter, r *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var document openapi.Document
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&document))
+	assert.Equal(t, "3.1.0", document.OpenAPI)
+	assert.Equal(t, openapi.Info{Title: "stores", Version: "1.2.3"}, document.Info)
+	assert.Len(t, document.Paths, 2, "patterns without a method are not documented")
+
+	create := (*document.Paths["/v1/stores/{store}/items"])["post"]
+	require.NotNil(t, create)
+	assert.Equal(t, "postV1StoresByStoreItems", create.OperationID)
+	assert.Equal(t, "Create an item", create.Summary)
+
+	var in []string
+	for _, p := range create.Parameters {
+		in = append(in, p.In+":"+p.Name)
+	}
+	assert.Equal(t, []string{"path:store", "query:tag", "query:limit", "header:X-Tenant"}, in)
+
+	assert.True(t, create.RequestBody.Required, "the body has required fields")
+	body := create.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, "#/components/schemas/createItem", body.Ref)
+	request := document.Components.Schemas["createItem"]
+	assert.Equal(t, []string{"name"}, request.Required)
+	assert.Len(t, request.Properties, 1, "bound fields are not part of the body")
+	assert.Equal(t, 8, *request.Properties["name"].MaxLength)
+
+	assert.Contains(t, create.Responses, "201")
+	assert.Contains(t, create.Responses, "400")
+	assert.Equal(t, "#/components/schemas/Problem", create.Responses["default"].Content[problemContentType].Schema.Ref)
+
+	remove := (*document.Paths["/stores/{store}"])["delete"]
+	require.NotNil(t, remove)
+	require.Len(t, remove.Parameters, 1)
+	assert.Equal(t, "store", remove.Parameters[0].Name)
+	assert.Nil(t, remove.RequestBody)
+
+	rename := (*document.Paths["/stores/{store}"])["patch"]
+	require.NotNil(t, rename)
+	assert.False(t, rename.RequestBody.Required, "a body without required fields is optional")
+}
+
+type renameStore struct {
+	Store string `path:"store" json:"-"`
+	Name  string `json:"name" validate:"max=8"`
+}
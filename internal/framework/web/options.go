@@ -1,7 +1,12 @@
 package web
 
 import (
+	"net/http"
+	"time"
+
+	"github.com/taylorono/go-webservice/internal/framework/health"
 	"github.com/taylorono/go-webservice/internal/framework/metrics"
+	"github.com/taylorono/go-webservice/internal/framework/openapi"
 )
 
 type OptionFunc func(*Server)
@@ -18,9 +23,36 @@ func WithDebugPort(port string) OptionFunc {
 	}
 }
 
+// WithDebugRoutes registers additional routes on the debug port, such as logging.Routes. They are only served when a
+// debug port is set.
+func WithDebugRoutes(routes ...func(mux *http.ServeMux)) OptionFunc {
+	return func(o *Server) {
+		o.debugRoutes = append(o.debugRoutes, routes...)
+	}
+}
+
+// WithMiddleware applies middleware to every route in the order given, the first being the outermost.
 func WithMiddleware(middleware ...Middleware) OptionFunc {
 	return func(o *Server) {
-		o.middleware = append(o.middleware, middleware...)
+		for _, m := range middleware {
+			o.middleware = append(o.middleware, namedMiddleware{middleware: m})
+		}
+	}
+}
+
+// WithServerMiddleware wraps the entire mux with middleware in the order given, the first being the outermost. Unlike
+// route middleware it also sees requests that match no route, but the matched pattern is only set on the request once the
+// mux has run.
+func WithServerMiddleware(middleware ...Middleware) OptionFunc {
+	return func(o *Server) {
+		o.serverMiddleware = append(o.serverMiddleware, middleware...)
+	}
+}
+
+// WithNamedMiddleware applies middleware to every route except those registered through a group created with Without(name).
+func WithNamedMiddleware(name string, middleware Middleware) OptionFunc {
+	return func(o *Server) {
+		o.middleware = append(o.middleware, namedMiddleware{name: name, middleware: middleware})
 	}
 }
 
@@ -29,7 +61,80 @@ func WithMetricRegistry(registry metrics.Reporter) OptionFunc {
 		// Register metrics routes before middleware to avoid instrumentation.
 		registry.Routes(o.mux)
 
-		// Add default instrumentation middleware
-		o.middleware = append(o.middleware, metrics.HttpMiddleware(registry))
+		o.metrics = registry
+
+		// Add default instrumentation middleware, routes may opt out with Without("metrics")
+		WithNamedMiddleware("metrics", metrics.HttpMiddleware(registry))(o)
+
+		// Record requests that match no route so 404 and 405 responses are visible
+		WithServerMiddleware(metrics.UnmatchedHttpMiddleware(registry))(o)
+	}
+}
+
+// WithHealthChecks replaces the server's health registry, exposing its checks on /livez, /readyz and /startupz.
+func WithHealthChecks(registry *health.Registry) OptionFunc {
+	return func(o *Server) {
+		o.health = registry
+	}
+}
+
+// WithShutdownDelay sets how long the server keeps serving after readiness starts failing, giving load balancers time to
+// stop routing traffic before the listeners close.
+func WithShutdownDelay(delay time.Duration) OptionFunc {
+	return func(o *Server) {
+		o.shutdownDelay = delay
+	}
+}
+
+// WithShutdownTimeout sets how long in-flight requests are given to complete before connections are forcibly closed.
+func WithShutdownTimeout(timeout time.Duration) OptionFunc {
+	return func(o *Server) {
+		o.shutdownTimeout = timeout
+	}
+}
+
+// WithTLS serves https using the given certificate and key, reloading them whenever the files change. TLS is disabled when
+// certFile is empty.
+func WithTLS(certFile, keyFile string) OptionFunc {
+	return func(o *Server) {
+		o.certFile = certFile
+		o.keyFile = keyFile
+	}
+}
+
+// WithClientCA requires clients to present a certificate signed by the given CA bundle. It has no effect without WithTLS.
+func WithClientCA(caFile string) OptionFunc {
+	return func(o *Server) {
+		o.clientCAFile = caFile
+	}
+}
+
+// WithErrorDetails includes the internal cause of errors in problem responses. It must not be enabled in production.
+func WithErrorDetails(enabled bool) OptionFunc {
+	return func(o *Server) {
+		o.errorDetails = enabled
+	}
+}
+
+// WithRepanic makes the server panic again once a panicking handler has been logged and answered, so that tests fail
+// loudly instead of only seeing a 500 response.
+func WithRepanic(enabled bool) OptionFunc {
+	return func(o *Server) {
+		o.repanic = enabled
+	}
+}
+
+// WithMaxBodyBytes caps the size of request bodies, a limit of 0 disables the cap.
+func WithMaxBodyBytes(limit int64) OptionFunc {
+	return func(o *Server) {
+		o.maxBodyBytes = limit
+	}
+}
+
+// WithOpenAPIInfo sets the title and version of the OpenAPI document served on /openapi.json, by default the module path
+// and version of the binary.
+func WithOpenAPIInfo(title, version string) OptionFunc {
+	return func(o *Server) {
+		o.openAPIInfo = openapi.Info{Title: title, Version: version}
 	}
 }
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylorono/go-webservice/internal/framework/metrics"
+)
+
+func TestWithMetricRegistry_MultiReporter(t *testing.T) {
+	first, second := metrics.NewPrometheusReporter(), metrics.NewPrometheusReporter()
+	reporter, err := metrics.NewMultiReporter(first, second)
+	require.NoError(t, err)
+
+	s := NewServer(WithMetricRegistry(reporter))
+	s.HandleFunc("GET /hello", func(w http.ResponseWriter, r *http.Request) {})
+	s.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+
+	for _, r := range []metrics.Reporter{first, second} {
+		mux := http.NewServeMux()
+		r.Routes(mux)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Contains(t, rec.Body.String(), `path="/hello"`, "every reporter records the request")
+	}
+
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,113 @@
+package web
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/taylorono/go-webservice/internal/framework/metrics"
+)
+
+const _panicsCounter = "app_panics_total"
+
+// recoverPanics wraps every route directly inside the metrics middleware, or outermost without one, so the metrics record a
+// recovered panic as the internal error it is answered with. Middleware registered before WithMetricRegistry wraps it and
+// sees that response, a panic raised by such middleware itself is not recovered. It turns a panicking handler into a
+// logged internal error problem and counts it, re-panicking afterwards when the server was created with WithRepanic. The http.ErrAbortHandler sentinel is passed
+// through so net/http aborts the response as intended.
+func (s *Server) recoverPanics(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tracker := &writeTracker{ResponseWriter: w}
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			slog.ErrorContext(r.Context(), "panic recovered",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("pattern", r.Pattern),
+				slog.String("panic", fmt.Sprint(recovered)),
+				slog.String("stack", string(debug.Stack())),
+			)
+			if s.metrics != nil {
+				s.metrics.IncCounter(_panicsCounter, 1, metrics.RoutePath(r))
+			}
+			metrics.SetErrorKind(r.Context(), string(KindInternal))
+
+			// a response that has started cannot be replaced, the client sees it cut short
+			if !tracker.written {
+				writeProblem(w, Problem{
+					Type:      "about:blank",
+					Title:     http.StatusText(http.StatusInternalServerError),
+					Status:    http.StatusInternalServerError,
+					Detail:    "an internal error occurred",
+					Instance:  r.URL.Path,
+					Kind:      KindInternal,
+					RequestID: requestID(r),
+				})
+			}
+
+			if s.repanic {
+				panic(recovered)
+			}
+		}()
+
+		next.ServeHTTP(tracker, r)
+	}
+}
+
+// writeTracker records whether the response has been started.
+type writeTracker struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (t *writeTracker) WriteHeader(code int) {
+	t.written = true
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *writeTracker) Write(b []byte) (int, error) {
+	t.written = true
+	return t.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses working for handlers asserting http.Flusher.
+func (t *writeTracker) Flush() {
+	t.written = true
+	_ = http.NewResponseController(t.ResponseWriter).Flush()
+}
+
+// Hijack keeps protocol upgrades working for handlers asserting http.Hijacker.
+func (t *writeTracker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(t.ResponseWriter).Hijack()
+	if err == nil {
+		// the connection belongs to the handler, no problem can be written to it
+		t.written = true
+	}
+	return conn, rw, err
+}
+
+// ReadFrom lets the underlying writer copy directly, for example with sendfile.
+func (t *writeTracker) ReadFrom(src io.Reader) (int64, error) {
+	t.written = true
+	if readerFrom, ok := t.ResponseWriter.(io.ReaderFrom); ok {
+		return readerFrom.ReadFrom(src)
+	}
+	return io.Copy(t.ResponseWriter, src)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (t *writeTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
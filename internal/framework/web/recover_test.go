@@ -0,0 +1,139 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylorono/go-webservice/internal/framework/logging"
+	"github.com/taylorono/go-webservice/internal/framework/metrics"
+)
+
+// counter records counters and ignores every other metric.
+type counter struct {
+	metrics.Registry
+	counts map[string]float64
+}
+
+func (c *counter) RegisterCounter(string, string, ...string) {}
+func (c *counter) RegisterGauge(string, string, ...string)   {}
+func (c *counter) SetGauge(string, float64, ...string)       {}
+
+func (c *counter) IncCounter(name string, value float64, labels ...string) {
+	c.counts[name+labels[0]] += value
+}
+
+func TestRecoverPanics(t *testing.T) {
+	logs := logging.Capture(t)
+	registry := &counter{counts: map[string]float64{}}
+	s := NewServer(func(s *Server) { s.metrics = registry })
+	s.HandleFunc("GET /boom/{id}", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/boom/1", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	var problem Problem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+	assert.Equal(t, KindInternal, problem.Kind)
+	assert.Equal(t, "abc", problem.RequestID)
+	assert.Equal(t, 1.0, registry.counts[_panicsCounter+"/boom/{id}"])
+
+	record, ok := logs.Find("panic recovered")
+	require.True(t, ok)
+	assert.Equal(t, "boom", record.Attrs["panic"])
+	assert.Equal(t, "abc", record.Attrs["request_id"])
+	assert.Equal(t, "GET /boom/{id}", record.Attrs["route"])
+	assert.Contains(t, record.Attrs["stack"], "runtime/debug.Stack")
+}
+
+func TestRecoverPanics_Repanic(t *testing.T) {
+	s := NewServer(WithRepanic(true))
+	s.HandleFunc("GET /boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	assert.PanicsWithValue(t, "boom", func() {
+		s.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/boom", nil))
+	})
+}
+
+func TestRecoverPanics_Metrics(t *testing.T) {
+	reporter := metrics.NewPrometheusReporter()
+	s := NewServer(WithMetricRegistry(reporter))
+	s.HandleFunc("GET /boom/{id}", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom/1", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	body := scrape(t, reporter)
+	assert.Contains(t, body, `app_request_latency_count{method="GET",path="/boom/{id}",status_code="500"} 1`,
+		"the response written on recovery is recorded")
+	assert.Contains(t, body, `app_request_errors_total{kind="internal",method="GET",path="/boom/{id}"} 1`)
+	assert.Contains(t, body, `app_panics_total{path="/boom/{id}"} 1`)
+}
+
+func TestRecoverPanics_MiddlewareBeforeMetrics(t *testing.T) {
+	// registered ahead of the metrics middleware like the request dumps of the service
+	var returned bool
+	outer := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r)
+			returned = true
+		}
+	}
+	reporter := metrics.NewPrometheusReporter()
+	s := NewServer(WithMiddleware(outer), WithMetricRegistry(reporter))
+	s.HandleFunc("GET /boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.True(t, returned, "the panic is recovered inside the middleware")
+	assert.Contains(t, scrape(t, reporter), `app_request_latency_count{method="GET",path="/boom",status_code="500"} 1`)
+}
+
+func TestServer_ResponseWriterInterfaces(t *testing.T) {
+	s := NewServer(WithMetricRegistry(metrics.NewPrometheusReporter()), WithMiddleware(logging.HttpLoggingMiddleware))
+	s.HandleFunc("GET /file", func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(io.ReaderFrom)
+		assert.True(t, ok, "copies reach the connection's ReadFrom")
+		_, _ = io.Copy(w, strings.NewReader("contents"))
+	})
+	s.HandleFunc("GET /upgrade", func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		require.True(t, ok, "protocol upgrades can take over the connection")
+		conn, rw, err := hijacker.Hijack()
+		require.NoError(t, err)
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nupgraded")
+		_ = rw.Flush()
+	})
+	ts := httptest.NewServer(s.handler())
+	t.Cleanup(ts.Close)
+
+	for path, want := range map[string]string{"/file": "contents", "/upgrade": "upgraded"} {
+		res, err := ts.Client().Get(ts.URL + path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, want, string(body))
+	}
+}
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/taylorono/go-webservice/internal/framework/logging"
+)
+
+// RequestIDHeader carries the id that correlates a request with its log records, in both the request and the response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds ids supplied by clients, longer ids are replaced.
+const maxRequestIDLength = 128
+
+// withRequestID accepts the client's request id or generates a UUID v7, echoes it on the response and stores it in the
+// request context where it is added to log records.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequest(r.Context(), id, r.Method)))
+	})
+}
+
+// withRoute adds the matched route pattern to the log records of the request.
+func withRoute(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logging.SetRoute(r.Context(), r.Pattern)
+		next(w, r)
+	}
+}
+
+func newRequestID() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		slog.Warn("failed to generate request id", slog.String("error", err.Error()))
+		return uuid.NewString()
+	}
+	return id.String()
+}
+
+// validRequestID accepts non-empty ids of printable ASCII so that client input cannot forge log lines or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestID returns the id of the request.
+func requestID(r *http.Request) string {
+	return logging.RequestID(r.Context())
+}
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylorono/go-webservice/internal/framework/logging"
+)
+
+func TestWithRequestID(t *testing.T) {
+	tests := map[string]struct {
+		header   string
+		generate bool
+	}{
+		"accepts client id":       {header: "client-id-1"},
+		"generates missing id":    {generate: true},
+		"replaces unprintable id": {header: "bad\tid", generate: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := NewServer()
+			var seen string
+			s.HandleFunc("GET /id", func(w http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/id", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			s.handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, seen, rec.Header().Get(RequestIDHeader))
+			if !tt.generate {
+				assert.Equal(t, tt.header, seen)
+				return
+			}
+			id, err := uuid.Parse(seen)
+			require.NoError(t, err)
+			assert.Equal(t, uuid.Version(7), id.Version())
+		})
+	}
+}
@@ -9,34 +9,70 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/taylorono/go-webservice/internal/framework/health"
+	"github.com/taylorono/go-webservice/internal/framework/metrics"
+	"github.com/taylorono/go-webservice/internal/framework/openapi"
 	"github.com/taylorono/go-webservice/internal/framework/profile"
 )
 
 func init() {
 	flag.String("port", "8080", "port to listen on")
 	flag.String("debug-port", "", "when set pprof will be enabled on this port")
+	flag.Duration("shutdown-delay", 5*time.Second, "time to keep serving after readiness fails before closing listeners")
+	flag.String("tls-cert-file", "", "when set the server will serve https using this certificate")
+	flag.String("tls-key-file", "", "private key for the tls certificate")
+	flag.String("tls-client-ca-file", "", "when set clients must present a certificate signed by this ca")
+	flag.Int64("max-body-bytes", 1<<20, "maximum size of a request body, 0 for unlimited")
+	flag.Bool("error-details", false, "include internal error details in problem responses, intended for debugging only")
+	flag.Duration("shutdown-timeout", 10*time.Second, "time to wait for in-flight requests before forcing connections closed")
 }
 
+// Middleware wraps a handler. When several middleware apply to a route the first one registered is the outermost, it
+// sees the request first and the response last.
 type Middleware func(next http.HandlerFunc) http.HandlerFunc
 
 // Server represents a web server suitable for kubernetes deployments.
 type Server struct {
-	port       string
-	debugPort  string
-	mux        *http.ServeMux
-	middleware []Middleware
+	port             string
+	debugPort        string
+	debugRoutes      []func(mux *http.ServeMux)
+	mux              *http.ServeMux
+	middleware       []namedMiddleware
+	serverMiddleware []Middleware
+	health           *health.Registry
+	metrics          metrics.Registry
+	shutdownDelay    time.Duration
+	shutdownTimeout  time.Duration
+	certFile         string
+	keyFile          string
+	clientCAFile     string
+	errorDetails     bool
+	repanic          bool
+	maxBodyBytes     int64
+	draining         atomic.Bool
+	inFlight         inFlight
+	openAPIInfo      openapi.Info
+	routesMu         sync.Mutex
+	routes           []route
 }
 
 // NewServer Creates a new web server with the given options.
 func NewServer(opts ...OptionFunc) *Server {
 	// default server
 	s := &Server{
-		port:       "8080",
-		mux:        http.NewServeMux(),
-		middleware: []Middleware{},
+		port:            "8080",
+		mux:             http.NewServeMux(),
+		middleware:      []namedMiddleware{},
+		health:          health.NewRegistry(),
+		shutdownDelay:   5 * time.Second,
+		shutdownTimeout: 10 * time.Second,
+		maxBodyBytes:    1 << 20,
+		openAPIInfo:     defaultOpenAPIInfo(),
 	}
 
 	// apply config overrides
@@ -44,17 +80,70 @@ func NewServer(opts ...OptionFunc) *Server {
 		opt(s)
 	}
 
+	// Recover panics in every route directly inside the metrics middleware so it records the response written, middleware
+	// registered before the metrics one wraps the recovered route
+	recoverAt := 0
+	if i := slices.IndexFunc(s.middleware, func(m namedMiddleware) bool { return m.name == "metrics" }); i >= 0 {
+		recoverAt = i + 1
+	}
+	s.middleware = slices.Insert(s.middleware, recoverAt, namedMiddleware{name: "recover", middleware: s.recoverPanics})
+
+	// Register health routes before middleware to avoid instrumentation.
+	s.health.AddCheck(health.Readiness, "shutdown", health.Uncached(health.CheckerFunc(s.readiness)))
+	s.health.Routes(s.mux)
+
+	// Serve the API description, built from the routes registered when it is requested.
+	s.mux.HandleFunc("GET /openapi.json", s.openAPIHandler)
+	s.mux.HandleFunc("GET /openapi", openapi.Viewer("openapi.json"))
+
+	if s.metrics != nil {
+		s.inFlight.register(s.metrics)
+		s.metrics.RegisterCounter(_panicsCounter, "Number of panics recovered from handlers", "path")
+	}
+
 	return s
 }
 
 // HandleFunc registers a new route with the given pattern and handler function applying any global middleware.
 func (s *Server) HandleFunc(pattern string, handler http.HandlerFunc) {
-	// apply any configured middleware
-	for _, m := range s.middleware {
-		handler = m(handler)
+	s.Group("").HandleFunc(pattern, handler)
+}
+
+func (s *Server) handleRoute(pattern string, handler http.HandlerFunc, doc *routeDoc) {
+	s.Group("").handleRoute(pattern, handler, doc)
+}
+
+// handle registers the route wrapping the handler so the first middleware is the outermost, and records it for the
+// OpenAPI document.
+func (s *Server) handle(pattern string, handler http.HandlerFunc, middleware []Middleware, doc *routeDoc) {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	s.mux.HandleFunc(pattern, withRoute(handler))
+
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
+	s.routes = append(s.routes, route{pattern: pattern, doc: doc})
+}
+
+// handler wraps the mux with the server middleware so the first is the outermost, then the internal wrappers that every
+// request passes through.
+func (s *Server) handler() http.Handler {
+	handler := http.HandlerFunc(s.mux.ServeHTTP)
+	for i := len(s.serverMiddleware) - 1; i >= 0; i-- {
+		handler = s.serverMiddleware[i](handler)
+	}
+
+	var wrapped http.Handler = handler
+	if s.errorDetails {
+		wrapped = withErrorDetails(wrapped)
+	}
+	if s.maxBodyBytes > 0 {
+		wrapped = limitBody(wrapped, s.maxBodyBytes)
 	}
 
-	s.mux.HandleFunc(pattern, handler)
+	return withRequestID(s.inFlight.track(withClientIdentity(wrapped)))
 }
 
 // Start starts the web server with the given context and will block until the context has been canceled. A context cancellation will cause a graceful shutdown.
@@ -64,13 +153,25 @@ func (s *Server) Start(ctx context.Context) error {
 	// Configure Server
 	httpServer := &http.Server{
 		Addr:    net.JoinHostPort("", s.port),
-		Handler: s.mux,
+		Handler: s.handler(),
+	}
+
+	// Configure TLS with certificates that reload when rotated on disk
+	if s.certFile != "" {
+		reloader, err := newCertReloader(s.certFile, s.keyFile, s.clientCAFile)
+		if err != nil {
+			return err
+		}
+		if err = reloader.watch(ctx); err != nil {
+			return err
+		}
+		httpServer.TLSConfig = reloader.tlsConfig()
 	}
 
 	// Server loop
 	go func() {
 		slog.Info(fmt.Sprintf("listening on %s\n", httpServer.Addr))
-		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := s.listenAndServe(httpServer); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			fmt.Fprintf(os.Stderr, "error listening and serving: %s\n", err)
 			ctx.Done()
 		}
@@ -78,17 +179,14 @@ func (s *Server) Start(ctx context.Context) error {
 
 	// Launch pprof if the port has been specified
 	if s.debugPort != "" {
-		profile.ListenAndServe(ctx, s.debugPort)
+		profile.ListenAndServe(ctx, s.debugPort, s.debugRoutes...)
 	}
 
 	// Allow for a graceful shutdown
 	var wg sync.WaitGroup
 	wg.Go(func() {
 		<-ctx.Done()
-		// Wait for 10 seconds before forcing a shutdown.
-		shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-		defer cancel()
-		if err = httpServer.Shutdown(shutdownCtx); err != nil {
+		if err = s.drain(httpServer); err != nil {
 			fmt.Fprintf(os.Stderr, "error shutting down http server: %s\n", err)
 		}
 	})
@@ -96,3 +194,19 @@ func (s *Server) Start(ctx context.Context) error {
 	wg.Wait()
 	return err
 }
+
+// limitBody caps the size of request bodies, reads past the limit fail with an *http.MaxBytesError.
+func limitBody(next http.Handler, limit int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listenAndServe(httpServer *http.Server) error {
+	if httpServer.TLSConfig != nil {
+		// certificates are served by the TLSConfig
+		return httpServer.ListenAndServeTLS("", "")
+	}
+	return httpServer.ListenAndServe()
+}
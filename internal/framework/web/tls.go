@@ -0,0 +1,188 @@
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/taylorono/go-webservice/internal/framework/logging"
+)
+
+type clientIdentityKey struct{}
+
+// ClientIdentity describes the verified certificate presented by a client over mutual TLS.
+type ClientIdentity struct {
+	Subject        string
+	CommonName     string
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []string
+	URIs           []string
+}
+
+// ClientIdentityFromContext returns the verified client certificate identity of the request, if any.
+func ClientIdentityFromContext(ctx context.Context) (ClientIdentity, bool) {
+	identity, ok := ctx.Value(clientIdentityKey{}).(ClientIdentity)
+	return identity, ok
+}
+
+// withClientIdentity stores the identity of a verified client certificate in the request context.
+func withClientIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cert := r.TLS.VerifiedChains[0][0]
+		identity := ClientIdentity{
+			Subject:        cert.Subject.String(),
+			CommonName:     cert.Subject.CommonName,
+			DNSNames:       cert.DNSNames,
+			EmailAddresses: cert.EmailAddresses,
+		}
+		for _, ip := range cert.IPAddresses {
+			identity.IPAddresses = append(identity.IPAddresses, ip.String())
+		}
+		for _, uri := range cert.URIs {
+			identity.URIs = append(identity.URIs, uri.String())
+		}
+
+		ctx := logging.WithAttrs(r.Context(), slog.String("client", identity.CommonName))
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, clientIdentityKey{}, identity)))
+	})
+}
+
+// certReloader serves the certificate and client CA pool from disk, reloading them when the files change.
+type certReloader struct {
+	sync.RWMutex
+	certFile  string
+	keyFile   string
+	caFile    string
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+func newCertReloader(certFile, keyFile, caFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if c.caFile != "" {
+		pem, err := os.ReadFile(c.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client ca: %w", err)
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("failed to parse client ca: no certificates found")
+		}
+	}
+
+	c.Lock()
+	c.cert = &cert
+	c.clientCAs = pool
+	c.Unlock()
+	return nil
+}
+
+func (c *certReloader) getCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.cert, nil
+}
+
+// tlsConfig returns a config that always serves the most recently loaded certificate and client CA pool.
+func (c *certReloader) tlsConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.getCertificate,
+	}
+
+	if c.caFile != "" {
+		config.GetConfigForClient = func(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+			c.RLock()
+			defer c.RUnlock()
+			return &tls.Config{
+				MinVersion:     tls.VersionTLS12,
+				GetCertificate: c.getCertificate,
+				ClientAuth:     tls.RequireAndVerifyClientCert,
+				ClientCAs:      c.clientCAs,
+			}, nil
+		}
+	}
+
+	return config
+}
+
+// watch reloads the certificates whenever their directories change until the context is canceled. Directories are watched
+// rather than files so that atomic renames and kubernetes secret symlink swaps are detected.
+func (c *certReloader) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create certificate watcher: %w", err)
+	}
+
+	dirs := map[string]struct{}{}
+	for _, file := range []string{c.certFile, c.keyFile, c.caFile} {
+		if file != "" {
+			dirs[filepath.Dir(file)] = struct{}{}
+		}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) && !event.Has(fsnotify.Remove) {
+					continue
+				}
+				// a rotation may touch the files one at a time, keep serving the previous pair until both are valid
+				if err := c.load(); err != nil {
+					slog.Warn("failed to reload certificates", slog.String("error", err.Error()))
+					continue
+				}
+				slog.Info("reloaded certificates", slog.String("file", event.Name))
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Error("certificate watcher error", slog.String("error", err.Error()))
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package web
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authority is a test certificate authority issuing server and client certificates.
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T) *authority {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM encoded certificate and key of a leaf for the common name.
+func (a *authority) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFiles writes the contents to the named files in dir.
+func writeFiles(t *testing.T, dir string, contents map[string][]byte) {
+	t.Helper()
+
+	for name, content := range contents {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o600))
+	}
+}
+
+// startTLS starts the server on a free port with the certificates in dir and returns its base URL.
+func startTLS(t *testing.T, dir string, clientCA bool, handler http.HandlerFunc) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, listener.Close())
+
+	opts := []OptionFunc{WithPort(port), WithShutdownDelay(0), WithTLS(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))}
+	if clientCA {
+		opts = append(opts, WithClientCA(filepath.Join(dir, "ca.crt")))
+	}
+	s := NewServer(opts...)
+	s.HandleFunc("GET /hello", handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		_ = s.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", port))
+		if err == nil {
+			_ = conn.Close()
+		}
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return "https://localhost:" + port
+}
+
+// client trusts the authority and presents the certificates, if any.
+func client(ca *authority, certificates ...tls.Certificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certificates, MinVersion: tls.VersionTLS12},
+	}}
+}
+
+func get(t *testing.T, client *http.Client, url string) (string, error) {
+	t.Helper()
+
+	res, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return string(body), err
+}
+
+func TestServer_TLS(t *testing.T) {
+	ca := newAuthority(t)
+	serverCert, serverKey := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{"tls.crt": serverCert, "tls.key": serverKey})
+
+	url := startTLS(t, dir, false, func(w http.ResponseWriter, r *http.Request) {
+		_, ok := ClientIdentityFromContext(r.Context())
+		_, _ = io.WriteString(w, map[bool]string{true: "identified", false: "anonymous"}[ok])
+	})
+
+	body, err := get(t, client(ca), url+"/hello")
+	require.NoError(t, err)
+	assert.Equal(t, "anonymous", body)
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	ca := newAuthority(t)
+	serverCert, serverKey := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, "orders", x509.ExtKeyUsageClientAuth)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{"tls.crt": serverCert, "tls.key": serverKey, "ca.crt": ca.pem})
+
+	url := startTLS(t, dir, true, func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := ClientIdentityFromContext(r.Context())
+		_, _ = io.WriteString(w, identity.CommonName)
+	})
+
+	_, err := get(t, client(ca), url+"/hello")
+	assert.Error(t, err, "clients without a certificate are rejected")
+
+	untrusted := newAuthority(t)
+	otherCert, otherKey := untrusted.issue(t, "intruder", x509.ExtKeyUsageClientAuth)
+	other, err := tls.X509KeyPair(otherCert, otherKey)
+	require.NoError(t, err)
+	_, err = get(t, client(ca, other), url+"/hello")
+	assert.Error(t, err, "clients with a certificate of another authority are rejected")
+
+	pair, err := tls.X509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+	body, err := get(t, client(ca, pair), url+"/hello")
+	require.NoError(t, err)
+	assert.Equal(t, "orders", body)
+}
+
+func TestCertReloader_Watch(t *testing.T) {
+	ca := newAuthority(t)
+	firstCert, firstKey := ca.issue(t, "first", x509.ExtKeyUsageServerAuth)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{"tls.crt": firstCert, "tls.key": firstKey})
+
+	reloader, err := newCertReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), "")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	require.NoError(t, reloader.watch(ctx))
+
+	commonName := func() string {
+		cert, err := reloader.getCertificate(nil)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		return leaf.Subject.CommonName
+	}
+	assert.Equal(t, "first", commonName())
+
+	writeFiles(t, dir, map[string][]byte{"tls.crt": []byte("not a certificate")})
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "first", commonName(), "an invalid certificate keeps the previous one")
+
+	secondCert, secondKey := ca.issue(t, "second", x509.ExtKeyUsageServerAuth)
+	writeFiles(t, dir, map[string][]byte{"tls.crt": secondCert, "tls.key": secondKey})
+	assert.Eventually(t, func() bool { return commonName() == "second" }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNewCertReloader_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{"ca.crt": []byte("not a certificate")})
+
+	_, err := newCertReloader(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key"), "")
+	assert.Error(t, err)
+
+	ca := newAuthority(t)
+	cert, key := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	writeFiles(t, dir, map[string][]byte{"tls.crt": cert, "tls.key": key})
+	_, err = newCertReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt"))
+	assert.ErrorContains(t, err, "failed to parse client ca")
+}
@@ -1,17 +1,23 @@
 package web
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
+
+	"github.com/taylorono/go-webservice/internal/framework/validation"
 )
 
+// Decode reads the request body into a T using the codec selected by its Content-Type and validates it against its
+// validate struct tags. Malformed bodies, unknown JSON fields, trailing data and invalid values are returned as a
+// validation *Error, unsupported media types as an unsupported media type *Error.
 func Decode[T any](r *http.Request) (T, error) {
 	var v T
-	if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
-		return v, fmt.Errorf("failed to request body: %w", err)
+	if err := decodeBody(r, &v); err != nil {
+		return v, err
+	}
+
+	if err := validation.Struct(&v); err != nil {
+		return v, NewValidationError("request validation failed", err)
 	}
 
-	_ = r.Body.Close()
 	return v, nil
 }
@@ -0,0 +1,120 @@
+package web
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var xmlUnmarshalerType = reflect.TypeFor[xml.Unmarshaler]()
+
+// xmlFields holds the element and attribute names a struct decodes from XML.
+type xmlFields struct {
+	elements   map[string]reflect.Type
+	attrs      map[string]bool
+	anyElement bool
+	anyAttr    bool
+}
+
+// checkXMLFields returns an error for the first element or attribute of the document that matches no field of t, the
+// type it was decoded into. Names are compared without their namespace. Types decoding themselves, and structs with an
+// ",any" or ",innerxml" field, accept any content.
+func checkXMLFields(data []byte, t reflect.Type) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return checkXMLElement(decoder, start, t)
+		}
+	}
+}
+
+func checkXMLElement(decoder *xml.Decoder, start xml.StartElement, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer || (t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8) {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || reflect.PointerTo(t).Implements(xmlUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return decoder.Skip()
+	}
+
+	fields := xmlFields{elements: map[string]reflect.Type{}, attrs: map[string]bool{}}
+	fields.add(t)
+
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" || fields.anyAttr || fields.attrs[attr.Name.Local] {
+			continue
+		}
+		return fmt.Errorf("unknown XML attribute %q", attr.Name.Local)
+	}
+	if fields.anyElement {
+		return decoder.Skip()
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			fieldType, ok := fields.elements[token.Name.Local]
+			if !ok {
+				return fmt.Errorf("unknown XML element %q", token.Name.Local)
+			}
+			if err := checkXMLElement(decoder, token, fieldType); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// add adds the fields of the struct type t, following the rules of encoding/xml.
+func (f *xmlFields) add(t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("xml")
+		if (!field.IsExported() && !field.Anonymous) || tag == "-" || field.Name == "XMLName" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if i := strings.LastIndex(name, " "); i >= 0 {
+			name = name[i+1:]
+		}
+		flags := strings.Split(options, ",")
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		switch {
+		case field.Anonymous && tag == "" && fieldType.Kind() == reflect.Struct:
+			f.add(fieldType)
+		case slices.Contains(flags, "attr"):
+			f.anyAttr = f.anyAttr || slices.Contains(flags, "any")
+			f.attrs[cmp.Or(name, field.Name)] = true
+		case slices.Contains(flags, "any"), slices.Contains(flags, "innerxml"):
+			f.anyElement = true
+		case slices.Contains(flags, "chardata"), slices.Contains(flags, "cdata"), slices.Contains(flags, "comment"):
+		default:
+			// a>b paths nest the field in elements that are not checked any further
+			parent, _, nested := strings.Cut(cmp.Or(name, field.Name), ">")
+			if nested {
+				f.elements[parent] = reflect.TypeFor[any]()
+				continue
+			}
+			f.elements[parent] = field.Type
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package service
 
+import (
+	"context"
+	"fmt"
+)
+
 type Service struct {
 }
 
@@ -10,3 +15,22 @@ func NewService() *Service {
 func (s *Service) SayHello() string {
 	return "Hello, World!"
 }
+
+type GreetRequest struct {
+	Name     string `path:"name" validate:"required,max=64"`
+	Greeting string `query:"greeting" validate:"max=32"`
+}
+
+type Greeting struct {
+	Message string `json:"message"`
+}
+
+// Greet greets the named person, using the requested greeting when one is given.
+func (s *Service) Greet(_ context.Context, req GreetRequest) (Greeting, error) {
+	greeting := req.Greeting
+	if greeting == "" {
+		greeting = "Hello"
+	}
+
+	return Greeting{Message: fmt.Sprintf("%s, %s!", greeting, req.Name)}, nil
+}